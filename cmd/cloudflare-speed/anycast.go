@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// probeIPs runs the anycast address comparison and prints each address with
+// its latency followed by the fastest one
+func probeIPs(opts options) error {
+	results, err := speedtest.NewClient(opts.test).ProbeAddresses(context.Background(), opts.test.Host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", opts.test.Host, err)
+	}
+
+	if opts.json {
+		return writeJSON(results, opts.jsonPretty)
+	}
+
+	fmt.Println("Cloudflare Speed Test")
+	for _, result := range results {
+		if result.Error != "" {
+			log.PrintPair(result.IP, "unreachable ("+result.Error+")", log.Bad)
+			continue
+		}
+		log.PrintFloat(result.IP, result.Latency, 2, "ms", log.Metric)
+	}
+	if len(results) > 0 && results[0].Error == "" {
+		log.PrintPair("Fastest", results[0].IP, log.Good)
+	}
+	return nil
+}
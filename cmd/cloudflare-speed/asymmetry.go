@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// asymmetryProbes is the number of probes sent in each direction
+const asymmetryProbes = 20
+
+// asymmetryProbe compares small download and upload latencies and prints
+// whether the two directions appear to differ
+func asymmetryProbe(opts options) error {
+	result, err := speedtest.NewClient(opts.test).ProbeAsymmetry(context.Background(), asymmetryProbes)
+	if err != nil {
+		return fmt.Errorf("failed to probe path asymmetry: %w", err)
+	}
+
+	if opts.json {
+		return writeJSON(result, opts.jsonPretty)
+	}
+
+	fmt.Println("Cloudflare Speed Test")
+	log.PrintFloat("Download latency", result.Download.Median, 2, "ms", log.Metric)
+	log.PrintFloat("Upload latency", result.Upload.Median, 2, "ms", log.Metric)
+	log.PrintFloat("Difference", result.Difference, 2, "ms", log.Metric)
+	if result.Asymmetric {
+		log.PrintPair("Paths", "likely asymmetric (difference exceeds the jitter)", log.Bad)
+	} else {
+		log.PrintPair("Paths", "no significant asymmetry", log.Good)
+	}
+	return nil
+}
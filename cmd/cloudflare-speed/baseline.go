@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// loadBaseline reads a result previously written with --json
+func loadBaseline(path string) (*speedtest.Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result speedtest.Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	return &result, nil
+}
+
+// baselineMetric is a metric compared between a baseline and a new run
+type baselineMetric struct {
+	name     string
+	unit     string
+	baseline float64
+	current  float64
+
+	// lowerIsBetter is set for metrics such as latency where an increase
+	// is a regression
+	lowerIsBetter bool
+}
+
+// change returns the relative change from the baseline in percent, positive
+// when the metric got better
+func (m baselineMetric) change() float64 {
+	if m.baseline == 0 {
+		return 0
+	}
+	change := (m.current - m.baseline) / m.baseline * 100
+	if m.lowerIsBetter {
+		change = -change
+	}
+	return change
+}
+
+// printBaselineComparison prints each metric's change from the baseline,
+// flagging those that got worse by more than threshold percent
+func printBaselineComparison(baseline, current *speedtest.Result, threshold float64) {
+	metrics := []baselineMetric{
+		{"Download", "Mbps", baseline.Download, current.Download, false},
+		{"Upload", "Mbps", baseline.Upload, current.Upload, false},
+		{"Latency", "ms", baseline.Latency, current.Latency, true},
+		{"Jitter", "ms", baseline.Jitter, current.Jitter, true},
+		{"TTFB", "ms", baseline.TTFB, current.TTFB, true},
+	}
+
+	log.PrintPair("Baseline", baseline.Timestamp, log.Info)
+	for _, m := range metrics {
+		change := m.change()
+		sign := "+"
+		if change < 0 {
+			sign = ""
+		}
+		detail := fmt.Sprintf("%s -> %s %s (%s%s%% %s)", log.FormatFloat(m.baseline, 2), log.FormatFloat(m.current, 2), m.unit,
+			sign, log.FormatFloat(change, 1), describeChange(change))
+		if change < -threshold {
+			log.PrintPair(m.name+" vs baseline", detail+" REGRESSION", log.Bad)
+			continue
+		}
+		log.PrintPair(m.name+" vs baseline", detail, log.Good)
+	}
+}
+
+// describeChange words a change so its sign is unambiguous for metrics where
+// lower is better
+func describeChange(change float64) string {
+	if change < 0 {
+		return "worse"
+	}
+	return "better"
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// concurrencyLevelDuration is how long each number of streams downloads
+const concurrencyLevelDuration = 5 * time.Second
+
+// concurrencyRamp downloads with more and more parallel streams until the
+// throughput stops increasing and prints each level and the knee
+func concurrencyRamp(opts options) error {
+	result, err := speedtest.NewClient(opts.test).MeasureConcurrency(context.Background(), opts.maxStreams, concurrencyLevelDuration)
+	if err != nil {
+		return fmt.Errorf("failed to measure concurrency: %w", err)
+	}
+
+	if opts.json {
+		return writeJSON(result, opts.jsonPretty)
+	}
+
+	fmt.Println("Cloudflare Speed Test")
+	for _, level := range result.Levels {
+		log.PrintFloat(strconv.Itoa(level.Streams)+" streams", level.Mbps, 2, "Mbps", log.Metric)
+	}
+	log.PrintFloat("Capacity", result.Capacity, 2, "Mbps", log.Good)
+	log.PrintPair("Saturated at", fmt.Sprintf("%d streams", result.Knee), log.Info)
+	return nil
+}
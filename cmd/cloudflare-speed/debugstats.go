@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+// debugPercentiles are the percentiles reported by --debug-stats
+var debugPercentiles = []float64{1, 5, 10, 25, 50, 75, 90, 95, 99}
+
+// debugHistogramBins is the number of bins in the --debug-stats histogram
+const debugHistogramBins = 10
+
+// debugStats is every statistic the math package computes for a set of samples
+type debugStats struct {
+	Samples           []float64                                    `json:"samples"`
+	Stats             math.Stats                                   `json:"stats"`
+	ConsecutiveJitter float64                                      `json:"consecutive_jitter"`
+	Percentiles       map[math.PercentileMethod]map[string]float64 `json:"percentiles"`
+	Histogram         debugHistogram                               `json:"histogram"`
+}
+
+// debugHistogram is the distribution of the samples, Counts[i] of them
+// falling between Edges[i] and Edges[i+1]
+type debugHistogram struct {
+	Edges  []float64 `json:"edges"`
+	Counts []int     `json:"counts"`
+}
+
+// debugStatsMode reads whitespace or comma separated samples from r and
+// writes their statistics as JSON, so the math can be checked against other tools
+func debugStatsMode(r io.Reader, opts options) error {
+	samples, err := readSamples(r)
+	if err != nil {
+		return err
+	}
+
+	out := debugStats{
+		Samples:           samples,
+		Stats:             math.Summarize(samples),
+		ConsecutiveJitter: math.ConsecutiveJitter(samples),
+		Percentiles:       make(map[math.PercentileMethod]map[string]float64),
+	}
+	for _, method := range []math.PercentileMethod{math.NearestRank, math.Interpolated} {
+		values := make(map[string]float64)
+		for _, p := range debugPercentiles {
+			values[fmt.Sprintf("p%g", p)] = math.Percentile(samples, p/100, method)
+		}
+		out.Percentiles[method] = values
+	}
+	out.Histogram.Edges, out.Histogram.Counts = math.Histogram(samples, debugHistogramBins)
+	return writeJSON(out, opts.jsonPretty)
+}
+
+// readSamples parses every number in r
+func readSamples(r io.Reader) ([]float64, error) {
+	var samples []float64
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		for _, field := range strings.Split(scanner.Text(), ",") {
+			if field == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sample %q", field)
+			}
+			samples = append(samples, v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no samples given on stdin")
+	}
+	return samples, nil
+}
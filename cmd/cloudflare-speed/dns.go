@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// dnsProbe times repeated lookups of the speed test host and prints a summary
+func dnsProbe(opts options) error {
+	if opts.dnsProbeCount <= 0 {
+		return fmt.Errorf("--dns-probe-count must be positive")
+	}
+	result, err := speedtest.NewClient(opts.test).ProbeDNS(context.Background(), opts.test.Host, opts.dnsProbeCount)
+	if err != nil {
+		return fmt.Errorf("failed to probe DNS: %w", err)
+	}
+
+	if opts.json {
+		return writeJSON(result, opts.jsonPretty)
+	}
+
+	fmt.Println("Cloudflare Speed Test")
+	log.PrintPair("Host", result.Host, log.Info)
+	log.PrintValue("Lookups", result.Probes, log.Info)
+	log.PrintValue("Failed lookups", result.Failed, log.Bad)
+	t := result.Timing
+	log.PrintFloat("Median lookup", t.Median, 2, "ms", log.Metric)
+	log.PrintFloat("Mean lookup", t.Mean, 2, "ms", log.Metric)
+	log.PrintFloat("Fastest lookup", t.Min, 2, "ms", log.Metric)
+	log.PrintFloat("Slowest lookup", t.Max, 2, "ms", log.Metric)
+	log.PrintFloat("Lookup jitter", t.Jitter, 2, "ms", log.Metric)
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// doctorTimeout bounds each individual doctor check
+const doctorTimeout = 10 * time.Second
+
+// doctorCheck is a single diagnostic, returning a short description of what
+// it found or an error explaining why it failed
+type doctorCheck struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+// doctor checks each dependency of a speed test in isolation, printing a
+// pass or fail line for each so users can see why a test fails
+func doctor(opts options) error {
+	client := speedtest.NewClient(opts.test)
+
+	checks := []doctorCheck{
+		{"Proxy", func(ctx context.Context) (string, error) {
+			if opts.test.Proxy != nil {
+				return opts.test.Proxy.Redacted(), nil
+			}
+			// Requests never use the proxy environment variables
+			for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+				if os.Getenv(name) != "" {
+					return "none (" + name + " is ignored, use --proxy)", nil
+				}
+			}
+			return "none", nil
+		}},
+		{"DNS", func(ctx context.Context) (string, error) {
+			switch {
+			case opts.test.UnixSocket != "":
+				return "skipped, connecting to " + opts.test.UnixSocket, nil
+			case opts.test.Proxy != nil:
+				return "skipped, the proxy resolves the host", nil
+			}
+			addrs, err := client.LookupHost(ctx)
+			if err != nil {
+				return "", err
+			}
+			return strings.Join(addrs, ", "), nil
+		}},
+		{"TCP", func(ctx context.Context) (string, error) {
+			addr, err := client.CheckConnection(ctx)
+			if err != nil {
+				return "", err
+			}
+			return "connected to " + addr, nil
+		}},
+		{"TLS", func(ctx context.Context) (string, error) {
+			if opts.test.Scheme == "http" {
+				return "skipped, the http scheme does not use TLS", nil
+			}
+			state, err := client.CheckTLS(ctx)
+			if err != nil {
+				return "", err
+			}
+			detail := speedtest.TLSVersionName(state.Version)
+			if len(state.PeerCertificates) > 0 {
+				detail += ", certificate issued by " + state.PeerCertificates[0].Issuer.CommonName
+			}
+			return detail, nil
+		}},
+		{"Trace endpoint", func(ctx context.Context) (string, error) {
+			trace, err := client.Trace(ctx)
+			if err != nil {
+				return "", err
+			}
+			return "colo " + trace.Colo, nil
+		}},
+		{"Locations endpoint", func(ctx context.Context) (string, error) {
+			locations, err := client.Locations(ctx)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d locations", len(locations)), nil
+		}},
+	}
+
+	fmt.Println("Cloudflare Speed Test doctor")
+	failed := 0
+	for _, check := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+		detail, err := check.run(ctx)
+		cancel()
+		if err != nil {
+			failed++
+			log.PrintPair(check.name, "FAIL "+err.Error(), log.Bad)
+			continue
+		}
+		log.PrintPair(check.name, "PASS "+detail, log.Good)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
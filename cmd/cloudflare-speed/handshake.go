@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// handshakeProbes is the number of connections set up by --measure-tls-only
+const handshakeProbes = 20
+
+// measureTLSOnly times repeated TCP connections and TLS handshakes and prints
+// their statistics
+func measureTLSOnly(opts options) error {
+	result, err := speedtest.NewClient(opts.test).MeasureHandshakes(context.Background(), handshakeProbes)
+	if err != nil {
+		return fmt.Errorf("failed to measure TLS handshakes: %w", err)
+	}
+
+	if opts.json {
+		return writeJSON(result, opts.jsonPretty)
+	}
+
+	fmt.Println("Cloudflare Speed Test")
+	log.PrintPair("TLS version", result.TLSVersion, log.Info)
+	log.PrintValue("Handshakes", result.Handshake.Count, log.Info)
+	log.PrintFloat("TCP connect", result.Connect.Median, 2, "ms", log.Metric)
+	log.PrintFloat("TLS handshake", result.Handshake.Median, 2, "ms", log.Metric)
+	log.PrintFloat("TLS handshake min", result.Handshake.Min, 2, "ms", log.Metric)
+	log.PrintFloat("TLS handshake max", result.Handshake.Max, 2, "ms", log.Metric)
+	log.PrintFloat("TLS handshake stddev", result.Handshake.StdDev, 2, "ms", log.Metric)
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// lossProbe runs the loss probes and prints the failure rate
+func lossProbe(opts options) error {
+	if opts.lossProbeCount <= 0 {
+		return fmt.Errorf("--loss-probe-count must be positive")
+	}
+	result := speedtest.NewClient(opts.test).ProbeLoss(context.Background(), opts.lossProbeCount)
+
+	if opts.json {
+		return writeJSON(result, opts.jsonPretty)
+	}
+
+	fmt.Println("Cloudflare Speed Test")
+	log.PrintValue("Loss probes", result.Probes, log.Info)
+	log.PrintValue("Failed probes", result.Failed, log.Bad)
+	log.PrintFloat("Estimated loss", result.LossPercent, 2, "%", log.Metric)
+	if result.Failed < result.Probes {
+		log.PrintFloat("Latency", result.Latency, 2, "ms", log.Metric)
+		log.PrintFloat("Jitter", result.Jitter, 2, "ms", log.Metric)
+		log.PrintPair("MOS estimate", log.FormatFloat(result.MOS, 2), log.Metric)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// writeMarkdown renders r as a markdown table for pasting into issues and
+// wikis, captioned with the server location and when the test ran
+func writeMarkdown(w io.Writer, r *speedtest.Result) error {
+	caption := "Cloudflare speed test"
+	if r.ServerColo != "" {
+		caption += fmt.Sprintf(" via %s (%s)", r.ServerCity, r.ServerColo)
+	}
+	caption += " at " + r.Timestamp
+
+	rows := [][2]string{
+		{"Download", fmt.Sprintf("%.2f Mbps", r.Download)},
+		{"Upload", fmt.Sprintf("%.2f Mbps", r.Upload)},
+		{"Latency", fmt.Sprintf("%.2f ms", r.Latency)},
+		{"Jitter", fmt.Sprintf("%.2f ms", r.Jitter)},
+	}
+	for _, tier := range r.Downloads {
+		rows = append(rows, [2]string{tier.Label + " download", fmt.Sprintf("%.2f Mbps", tier.Speed)})
+	}
+	for _, tier := range r.Uploads {
+		rows = append(rows, [2]string{tier.Label + " upload", fmt.Sprintf("%.2f Mbps", tier.Speed)})
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n\n", escapeMarkdown(caption))
+	b.WriteString("| Metric | Value |\n| --- | ---: |\n")
+	for _, row := range rows {
+		fmt.Fprintf(&b, "| %s | %s |\n", escapeMarkdown(row[0]), row[1])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`")
+
+// escapeMarkdown escapes the characters that would break a table cell or
+// turn into formatting
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
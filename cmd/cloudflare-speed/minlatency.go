@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// minLatencyResult is the JSON output of --min-latency
+type minLatencyResult struct {
+	// Floor is the lowest latency seen, the round trip of the uncongested path
+	Floor   float64         `json:"floor_ms"`
+	Latency speedtest.Stats `json:"latency_ms"`
+}
+
+// minLatency sends many small latency probes back to back and reports the
+// lowest, which is what the path can do when nothing else is queued on it.
+// The default --latency-budget is sized for a run's 20 probes, so it only
+// applies when given explicitly.
+func minLatency(opts options) error {
+	if opts.minLatencyProbes <= 0 {
+		return fmt.Errorf("--min-latency-probes must be positive")
+	}
+	budgetSet := false
+	flag.Visit(func(f *flag.Flag) {
+		budgetSet = budgetSet || f.Name == "latency-budget"
+	})
+	if !budgetSet {
+		opts.test.LatencyBudget = 0
+	}
+
+	stats, err := speedtest.NewClient(opts.test).Ping(context.Background(), opts.minLatencyProbes)
+	if err != nil {
+		return fmt.Errorf("failed to measure latency: %w", err)
+	}
+	if stats.Count < opts.minLatencyProbes {
+		fmt.Fprintf(os.Stderr, "Warning: only %d of %d probes completed", stats.Count, opts.minLatencyProbes)
+		if budgetSet && opts.test.LatencyBudget > 0 {
+			fmt.Fprintf(os.Stderr, " within --latency-budget %s", opts.test.LatencyBudget)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if opts.json {
+		return writeJSON(minLatencyResult{Floor: stats.Min, Latency: stats}, opts.jsonPretty)
+	}
+
+	fmt.Println("Cloudflare Speed Test")
+	log.PrintFloat("Latency floor", stats.Min, 2, "ms", log.Good)
+	log.PrintFloat("Median latency", stats.Median, 2, "ms", log.Metric)
+	log.PrintFloat("Median above floor", stats.Median-stats.Min, 2, "ms", log.Metric)
+	log.PrintFloat("Slowest probe", stats.Max, 2, "ms", log.Metric)
+	log.PrintValue("Probes", stats.Count, log.Info)
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// multiRunResult is the outcome of one run of a multi-run, as reported in
+// the JSON output
+type multiRunResult struct {
+	Host   string            `json:"host"`
+	Run    int               `json:"run"`
+	Result *speedtest.Result `json:"result,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// workerPool calls fn for each index from 0 to n-1 using at most workers
+// goroutines at once, and returns once every call has finished
+func workerPool(n, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// multiRun tests each of --hosts --repeat times, running up to --concurrency
+// tests at once. Concurrent tests share the link, so each measures less than
+// the full speed; use it to compare hosts or gather samples quickly rather
+// than to measure the link. --min-interval applies to the batch as a whole.
+func multiRun(opts options) error {
+	hosts := opts.hosts
+	if len(hosts) == 0 {
+		hosts = []string{opts.test.Host}
+	}
+	if opts.repeat < 1 {
+		return fmt.Errorf("--repeat must be positive")
+	}
+	if opts.concurrency < 1 {
+		return fmt.Errorf("--concurrency must be positive")
+	}
+	if opts.minInterval > 0 {
+		if err := claimRun(opts.stateFile, opts.minInterval, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	runs := make([]multiRunResult, 0, len(hosts)*opts.repeat)
+	for _, host := range hosts {
+		for i := 1; i <= opts.repeat; i++ {
+			runs = append(runs, multiRunResult{Host: host, Run: i})
+		}
+	}
+
+	// Each worker writes only its own slot so results keep their order
+	workerPool(len(runs), opts.concurrency, func(i int) {
+		test := opts.test
+		test.Host = runs[i].Host
+		result, err := speedtest.NewClient(test).Run(context.Background())
+		if err != nil {
+			runs[i].Error = err.Error()
+			return
+		}
+		result.Timestamp = opts.timeFormat.format(result.Started)
+		result.Version = currentBuild().Version
+		runs[i].Result = result
+	})
+
+	failed := 0
+	for _, run := range runs {
+		if run.Error != "" {
+			failed++
+			continue
+		}
+		if opts.sqlite != "" {
+			if err := writeSQLite(opts.sqlite, run.Result); err != nil {
+				return fmt.Errorf("failed to record result in %s: %w", opts.sqlite, err)
+			}
+		}
+	}
+
+	switch {
+	case opts.json:
+		if err := writeJSON(runs, opts.jsonPretty); err != nil {
+			return err
+		}
+	case opts.oneline:
+		for _, run := range runs {
+			if run.Error != "" {
+				fmt.Printf("%s #%d | error: %s\n", run.Host, run.Run, run.Error)
+				continue
+			}
+			fmt.Printf("%s #%d | ", run.Host, run.Run)
+			printOneline(run.Result)
+		}
+	default:
+		fmt.Println("Cloudflare Speed Test")
+		for i, run := range runs {
+			if i > 0 {
+				fmt.Println()
+			}
+			log.PrintPair("Run", fmt.Sprintf("%d of %d against %s", run.Run, opts.repeat, run.Host), log.Info)
+			if run.Error != "" {
+				log.PrintPair("Error", run.Error, log.Bad)
+				continue
+			}
+			printResult(run.Result)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d runs failed", failed, len(runs))
+	}
+	return nil
+}
+
+// parseHostList parses a comma separated list of hosts
+func parseHostList(value string) []string {
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/coleaeason/cloudflare-speed/internal/analysis"
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// printResult prints the human readable summary of a run
+func printResult(r *speedtest.Result) {
+	if len(r.Interception) > 0 {
+		log.PrintPair("WARNING", "the connection appears to be intercepted by a captive portal or proxy; results below are likely wrong", log.Bad)
+		for _, warning := range r.Interception {
+			log.PrintPair("Interception", warning, log.Bad)
+		}
+	}
+
+	log.PrintPair("Test time", r.Timestamp, log.Info)
+	if c := r.Clock; c != nil {
+		log.PrintPair("Clock offset", fmt.Sprintf("%s ms from %s (±%s)", log.FormatFloat(c.Offset, 2), c.Server, log.FormatFloat(c.RoundTrip/2, 2)), log.Info)
+	}
+	if r.ServerColo != "" {
+		log.PrintPair("Server location", fmt.Sprintf("%s (%s)", r.ServerCity, r.ServerColo), log.Info)
+		log.PrintPair("Your IP", fmt.Sprintf("%s (%s)", r.IP, r.Location), log.Info)
+	}
+
+	log.PrintFloat("Latency", r.Latency, 2, "ms", log.Metric)
+	if r.LatencyMethod == speedtest.TCPLatency {
+		log.PrintPair("Latency method", "TCP handshake", log.Info)
+	} else if r.ServerTimingIgnored {
+		log.PrintPair("Latency method", "time to first byte, Server-Timing ignored", log.Info)
+	}
+	if r.LatencyMode > 0 {
+		log.PrintFloat("Latency mode", r.LatencyMode, 2, "ms", log.Metric)
+	}
+	log.PrintFloat("Jitter", r.Jitter, 2, "ms", log.Metric)
+	log.PrintFloat("TTFB", r.TTFB, 2, "ms", log.Metric)
+	log.PrintValue("Latency probes", r.LatencyProbes, log.Metric)
+	if l := r.LoadedLatency; l != nil {
+		if l.DownloadGrade != "" {
+			log.PrintPair("Download loaded latency", fmt.Sprintf("%s ms (+%s, grade %s)",
+				log.FormatFloat(l.Download, 2), log.FormatFloat(l.DownloadIncrease, 2), l.DownloadGrade), log.Metric)
+		}
+		if l.UploadGrade != "" {
+			log.PrintPair("Upload loaded latency", fmt.Sprintf("%s ms (+%s, grade %s)",
+				log.FormatFloat(l.Upload, 2), log.FormatFloat(l.UploadIncrease, 2), l.UploadGrade), log.Metric)
+		}
+		log.PrintPair("Bufferbloat grade", l.Grade, log.Info)
+	}
+	log.PrintPair("TLS version", r.TLSVersion, log.Info)
+
+	printSpeedTable(r)
+	for _, tier := range r.Downloads {
+		if tier.TTFB > 0 {
+			log.PrintFloat(tier.Label+" TTFB", tier.TTFB, 2, "ms", log.Metric)
+		}
+	}
+	for _, tier := range r.Downloads {
+		if tier.Samples > 0 {
+			log.PrintValue(tier.Label+" download samples", tier.Samples, log.Metric)
+		}
+	}
+	for _, tier := range r.Uploads {
+		if tier.Samples > 0 {
+			log.PrintValue(tier.Label+" upload samples", tier.Samples, log.Metric)
+		}
+	}
+	log.PrintFloat("Download speed", r.Download, 2, "Mbps", log.Good)
+	stabilityColor := log.Good
+	switch r.DownloadStability.Label {
+	case "variable":
+		stabilityColor = log.Warn
+	case "unstable":
+		stabilityColor = log.Bad
+	}
+	log.PrintPair("Download stability", fmt.Sprintf("%s (coefficient of variation %s)",
+		r.DownloadStability.Label, log.FormatFloat(r.DownloadStability.CV, 2)), stabilityColor)
+	log.PrintFloat("Upload speed", r.Upload, 2, "Mbps", log.Good)
+	if len(r.DownloadPercentiles) > 0 {
+		log.PrintPair("Download percentiles", describePercentiles(r.DownloadPercentiles), log.Good)
+		log.PrintPair("Upload percentiles", describePercentiles(r.UploadPercentiles), log.Good)
+	}
+	log.PrintPair("Upload timing", fmt.Sprintf("%s (server %s Mbps, client %s Mbps)", r.UploadTiming,
+		log.FormatFloat(r.UploadServer, 2), log.FormatFloat(r.UploadClient, 2)), log.Info)
+	log.PrintPair("Aggregate", fmt.Sprintf("p%g (%s)", r.Percentile, r.PercentileMethod), log.Info)
+	printGrade(r.Grade)
+
+	log.PrintPair("Streaming", r.AIM.Streaming.Classification, log.Info)
+	log.PrintPair("Gaming", r.AIM.Gaming.Classification, log.Info)
+	log.PrintPair("Video chatting", r.AIM.VideoChat.Classification, log.Info)
+	log.PrintPair("Bandwidth-delay product", describeBDP(r.BDP), log.Info)
+
+	rel := r.Reliability
+	reliabilityColor := log.Good
+	if rel.Failed > 0 {
+		reliabilityColor = log.Bad
+	}
+	log.PrintPair("Request success", fmt.Sprintf("%s%% (%d of %d failed)", log.FormatFloat(rel.SuccessPercent, 1), rel.Failed, rel.Attempts), reliabilityColor)
+
+	for _, redirect := range r.Redirects {
+		log.PrintPair("Redirected", redirect, log.Bad)
+	}
+
+	t := r.Timings
+	log.PrintPair("Test duration", fmt.Sprintf("%ss (latency %ss, metadata %ss, download %ss, upload %ss)",
+		log.FormatFloat(t.Total, 1), log.FormatFloat(t.Latency, 1), log.FormatFloat(t.Metadata, 1),
+		log.FormatFloat(t.Download, 1), log.FormatFloat(t.Upload, 1)), log.Info)
+
+	for _, warning := range r.Warnings {
+		log.PrintPair("Warning", warning, log.Warn)
+	}
+}
+
+// printTraffic prints the requests and bytes of each phase, for reconciling
+// with a router's traffic counters
+func printTraffic(r *speedtest.Result) {
+	t := r.Traffic
+	phases := []struct {
+		name    string
+		traffic speedtest.PhaseTraffic
+	}{
+		{"Latency", t.Latency},
+		{"Metadata", t.Metadata},
+		{"Download", t.Download},
+		{"Upload", t.Upload},
+		{"Total", t.Total},
+	}
+	for _, phase := range phases {
+		log.PrintPair(phase.name+" traffic", fmt.Sprintf("%d requests, %s bytes sent, %s bytes received", phase.traffic.Requests,
+			log.FormatFloat(float64(phase.traffic.BytesSent), 0), log.FormatFloat(float64(phase.traffic.BytesReceived), 0)), log.Info)
+	}
+}
+
+// printGrade prints the overall grade and the sub-score of each metric
+func printGrade(g speedtest.Grade) {
+	color := log.Good
+	switch g.Letter {
+	case "C":
+		color = log.Warn
+	case "D", "F":
+		color = log.Bad
+	}
+	c := g.Components
+	log.PrintPair("Grade", fmt.Sprintf("%s (%s/100: download %s, upload %s, latency %s, jitter %s)", g.Letter,
+		log.FormatFloat(g.Score, 0), log.FormatFloat(c.Download, 0), log.FormatFloat(c.Upload, 0),
+		log.FormatFloat(c.Latency, 0), log.FormatFloat(c.Jitter, 0)), color)
+}
+
+// describePercentiles renders percentiles as "p50 12.00, p90 15.00 Mbps"
+func describePercentiles(values []speedtest.PercentileValue) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("p%g %s", v.Percentile, log.FormatFloat(v.Mbps, 2))
+	}
+	return strings.Join(parts, ", ") + " Mbps"
+}
+
+// describeBDP renders a bandwidth-delay product with what it means for TCP tuning
+func describeBDP(bytes float64) string {
+	size := fmt.Sprintf("%s bytes (%s KiB)", log.FormatFloat(bytes, 0), log.FormatFloat(bytes/1024, 1))
+	if bytes > analysis.DefaultTCPWindow {
+		return size + ", needs TCP window scaling to reach full download speed"
+	}
+	return size + ", fits in a default 64 KiB TCP window"
+}
+
+// printSpeedTable prints min, median, percentile and max speeds side by side
+// for each tier and for all download and upload samples. The table is left
+// unstyled since escape sequences would break the column alignment.
+func printSpeedTable(r *speedtest.Result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "Mbps\tmin\tmedian\tp%g\tmax\t\n", r.Percentile)
+	row := func(label string, s speedtest.SpeedStats) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", label, log.FormatFloat(s.Min, 2), log.FormatFloat(s.Median, 2),
+			log.FormatFloat(s.Percentile, 2), log.FormatFloat(s.Max, 2))
+	}
+	for _, tier := range r.Downloads {
+		row(tier.Label+" download", tier.Stats)
+	}
+	row("Download", r.DownloadStats)
+	for _, tier := range r.Uploads {
+		row(tier.Label+" upload", tier.Stats)
+	}
+	row("Upload", r.UploadStats)
+	w.Flush()
+}
+
+// writeJSON writes v to stdout as JSON, indented when pretty is set
+func writeJSON(v interface{}, pretty bool) error {
+	return encodeJSON(os.Stdout, v, pretty)
+}
+
+// encodeJSON writes v to w as JSON, indented when pretty is set
+func encodeJSON(w io.Writer, v interface{}, pretty bool) error {
+	enc := json.NewEncoder(w)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+// writeToFD writes v as JSON to the already open file descriptor fd, which
+// the calling process set up, e.g. a named pipe opened with 3>pipe. The
+// standard streams are written through their existing files and left open
+// for the rest of the output.
+func writeToFD(fd int, v interface{}, pretty bool) error {
+	if std := []*os.File{os.Stdin, os.Stdout, os.Stderr}; fd < len(std) {
+		return encodeJSON(std[fd], v, pretty)
+	}
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+	if f == nil {
+		return fmt.Errorf("invalid file descriptor")
+	}
+	defer f.Close()
+	return encodeJSON(f, v, pretty)
+}
+
+// printOneline prints a compact summary suitable for status bars, e.g.
+// "↓95.20 ↑12.40 Mbps | 12.00ms ±1.20 | EWR". The location is left out when
+// it is unknown.
+func printOneline(r *speedtest.Result) {
+	line := fmt.Sprintf("↓%.2f ↑%.2f Mbps | %.2fms ±%.2f", r.Download, r.Upload, r.Latency, r.Jitter)
+	if r.ServerColo != "" {
+		line += " | " + r.ServerColo
+	}
+	fmt.Println(line)
+}
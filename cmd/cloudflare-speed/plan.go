@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// dryRun prints the estimated requests, data and time of a run without
+// making any requests
+func dryRun(opts options) error {
+	plan := speedtest.NewClient(opts.test).Plan(opts.assumedSpeed / 1e6)
+	if opts.json {
+		return writeJSON(plan, opts.jsonPretty)
+	}
+
+	fmt.Println("Cloudflare Speed Test plan")
+	log.PrintPair("Assumed speed", log.FormatFloat(plan.AssumedMbps, 2)+" Mbps", log.Info)
+	log.PrintValue("Max retries", opts.test.MaxRetries, log.Info)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "phase\trequests\tMB\tseconds\tworst requests\tworst MB\tworst seconds\t")
+	for _, phase := range append(plan.Phases, plan.Total) {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%d\t%s\t%s\t\n", phase.Name,
+			phase.Requests, log.FormatFloat(float64(phase.Bytes)/1e6, 1), log.FormatFloat(phase.Seconds, 1),
+			phase.WorstRequests, log.FormatFloat(float64(phase.WorstBytes)/1e6, 1), log.FormatFloat(phase.WorstSeconds, 1))
+	}
+	return w.Flush()
+}
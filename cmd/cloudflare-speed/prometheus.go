@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// writePrometheus writes r and the smoothed metrics in the Prometheus text
+// exposition format. Only the error counter is written until the first run
+// has completed.
+func writePrometheus(w io.Writer, r *speedtest.Result, s smoothedMetrics, errors int) {
+	fmt.Fprintln(w, "# HELP cloudflare_speed_run_errors_total Speed test runs that failed.")
+	fmt.Fprintln(w, "# TYPE cloudflare_speed_run_errors_total counter")
+	fmt.Fprintf(w, "cloudflare_speed_run_errors_total %d\n", errors)
+	if r == nil {
+		return
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"cloudflare_speed_download_mbps", "Download speed in megabits per second.", r.Download},
+		{"cloudflare_speed_upload_mbps", "Upload speed in megabits per second.", r.Upload},
+		{"cloudflare_speed_latency_ms", "Median latency in milliseconds.", r.Latency},
+		{"cloudflare_speed_jitter_ms", "Latency jitter in milliseconds.", r.Jitter},
+		{"cloudflare_speed_ttfb_ms", "Median time to first byte in milliseconds.", r.TTFB},
+		{"cloudflare_speed_download_mbps_smoothed", "EWMA of the download speed across runs.", s.Download},
+		{"cloudflare_speed_upload_mbps_smoothed", "EWMA of the upload speed across runs.", s.Upload},
+		{"cloudflare_speed_latency_ms_smoothed", "EWMA of the median latency across runs.", s.Latency},
+		{"cloudflare_speed_jitter_ms_smoothed", "EWMA of the jitter across runs.", s.Jitter},
+		{"cloudflare_speed_ttfb_ms_smoothed", "EWMA of the median time to first byte across runs.", s.TTFB},
+		{"cloudflare_speed_duration_seconds", "Duration of the last run in seconds.", r.Timings.Total},
+		{"cloudflare_speed_last_run_timestamp_seconds", "Unix time the last run started.", float64(r.Started.Unix())},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n", g.name, g.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+		fmt.Fprintf(w, "%s{colo=%q} %g\n", g.name, r.ServerColo, g.value)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// parseProxy parses the --proxy URL, applying --proxy-auth credentials in
+// "user:password" form over any in the URL. Errors never include the URL or
+// credentials, which would otherwise end up in shell history and logs.
+func parseProxy(raw, auth string) (*url.URL, error) {
+	if raw == "" {
+		if auth != "" {
+			return nil, errors.New("--proxy-auth requires --proxy")
+		}
+		return nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, errors.New("--proxy must be a URL such as http://proxy:8080")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, errors.New("--proxy scheme must be http, https or socks5")
+	}
+
+	if auth != "" {
+		user, password, ok := strings.Cut(auth, ":")
+		if !ok || user == "" {
+			return nil, errors.New("--proxy-auth must be in user:password form")
+		}
+		u.User = url.UserPassword(user, password)
+	}
+	return u, nil
+}
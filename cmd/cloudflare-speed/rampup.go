@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// rampUp measures how long a large download takes to reach its sustained
+// rate and prints the throughput over time
+func rampUp(opts options) error {
+	result, err := speedtest.NewClient(opts.test).MeasureRampUp(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to measure ramp-up: %w", err)
+	}
+
+	if opts.json {
+		return writeJSON(result, opts.jsonPretty)
+	}
+
+	fmt.Println("Cloudflare Speed Test")
+	for _, s := range result.Samples {
+		log.PrintFloat(log.FormatFloat(s.Seconds, 2)+"s", s.Mbps, 2, "Mbps", log.Metric)
+	}
+	log.PrintFloat("Steady state", result.SteadyState, 2, "Mbps", log.Good)
+	log.PrintFloat("Ramp-up time", result.RampUp, 2, "s", log.Info)
+	return nil
+}
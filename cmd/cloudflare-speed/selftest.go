@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coleaeason/cloudflare-speed/internal/log"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// selftest runs speedtest.SelfTest and prints a pass or fail line for each
+// of its checks
+func selftest(opts options) error {
+	fmt.Println("Cloudflare Speed Test self-check")
+	checks, err := speedtest.SelfTest(context.Background())
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, check := range checks {
+		detail := fmt.Sprintf("%.2f %s (expected %.2f to %.2f)", check.Got, check.Unit, check.Min, check.Max)
+		if !check.Passed() {
+			failed++
+			log.PrintPair(check.Name, "FAIL "+detail, log.Bad)
+			continue
+		}
+		log.PrintPair(check.Name, "PASS "+detail, log.Good)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// smoothedMetrics are the headline metrics averaged across runs with an EWMA
+// to take the noise out of dashboards
+type smoothedMetrics struct {
+	Download float64 `json:"download_mbps"`
+	Upload   float64 `json:"upload_mbps"`
+	Latency  float64 `json:"latency_ms"`
+	Jitter   float64 `json:"jitter_ms"`
+	TTFB     float64 `json:"ttfb_ms"`
+
+	DownloadBytesPerSecond float64 `json:"download_bytes_per_second"`
+	UploadBytesPerSecond   float64 `json:"upload_bytes_per_second"`
+}
+
+// update folds a new result into the averages. The first result seeds them.
+func (s *smoothedMetrics) update(r *speedtest.Result, alpha float64, first bool) {
+	if first {
+		*s = smoothedMetrics{Download: r.Download, Upload: r.Upload, Latency: r.Latency, Jitter: r.Jitter, TTFB: r.TTFB}
+	} else {
+		s.Download = math.EWMA(s.Download, r.Download, alpha)
+		s.Upload = math.EWMA(s.Upload, r.Upload, alpha)
+		s.Latency = math.EWMA(s.Latency, r.Latency, alpha)
+		s.Jitter = math.EWMA(s.Jitter, r.Jitter, alpha)
+		s.TTFB = math.EWMA(s.TTFB, r.TTFB, alpha)
+	}
+	s.DownloadBytesPerSecond = speedtest.BytesPerSecond(s.Download)
+	s.UploadBytesPerSecond = speedtest.BytesPerSecond(s.Upload)
+}
+
+// daemon runs speed tests on an interval and serves the most recent result
+type daemon struct {
+	opts   options
+	client *speedtest.Client
+	rng    *rand.Rand
+
+	mu       sync.Mutex
+	latest   *speedtest.Result
+	smoothed smoothedMetrics
+	errors   int
+}
+
+// serve runs the daemon until the HTTP server fails
+func serve(opts options) error {
+	if opts.interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	if opts.intervalJitter < 0 {
+		return fmt.Errorf("--interval-jitter must not be negative")
+	}
+	if opts.ewmaAlpha <= 0 || opts.ewmaAlpha > 1 {
+		return fmt.Errorf("--ewma-alpha must be greater than 0 and at most 1")
+	}
+
+	d := &daemon{
+		opts:   opts,
+		client: speedtest.NewClient(opts.test),
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	go d.loop()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", d.handleMetrics)
+	mux.HandleFunc("/results.json", d.handleResults)
+	mux.HandleFunc("/smoothed.json", d.handleSmoothed)
+
+	fmt.Fprintf(os.Stderr, "Serving metrics on %s, testing every %s\n", opts.serve, opts.interval)
+	return http.ListenAndServe(opts.serve, mux)
+}
+
+// loop runs a test immediately and then once per interval, or as soon as
+// SIGUSR1 is received. Signals arriving during a run start one more run
+// straight after it.
+func (d *daemon) loop() {
+	trigger := make(chan os.Signal, 1)
+	notifyRunSignal(trigger)
+	for {
+		d.runOnce()
+		timer := time.NewTimer(d.nextInterval())
+		select {
+		case <-timer.C:
+		case <-trigger:
+			timer.Stop()
+			fmt.Fprintf(os.Stderr, "Received SIGUSR1, running a test now\n")
+		}
+	}
+}
+
+// nextInterval is the interval plus a random share of the interval jitter.
+// Instances started together drift apart instead of all testing at once.
+func (d *daemon) nextInterval() time.Duration {
+	if d.opts.intervalJitter <= 0 {
+		return d.opts.interval
+	}
+	return d.opts.interval + time.Duration(d.rng.Int63n(int64(d.opts.intervalJitter)+1))
+}
+
+func (d *daemon) runOnce() {
+	result, err := d.client.Run(context.Background())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		d.errors++
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	result.Timestamp = d.opts.timeFormat.format(result.Started)
+	result.Version = currentBuild().Version
+	d.smoothed.update(result, d.opts.ewmaAlpha, d.latest == nil)
+	d.latest = result
+
+	if d.opts.sqlite != "" {
+		if err := writeSQLite(d.opts.sqlite, result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to record result in %s: %v\n", d.opts.sqlite, err)
+		}
+	}
+}
+
+// snapshot returns the latest result, which is nil until a run succeeds, the
+// smoothed metrics and the error count
+func (d *daemon) snapshot() (*speedtest.Result, smoothedMetrics, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.latest, d.smoothed, d.errors
+}
+
+func (d *daemon) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	latest, smoothed, errors := d.snapshot()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writePrometheus(w, latest, smoothed, errors)
+}
+
+func (d *daemon) handleSmoothed(w http.ResponseWriter, r *http.Request) {
+	latest, smoothed, _ := d.snapshot()
+	if latest == nil {
+		http.Error(w, "no completed test yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := encodeJSON(w, smoothed, d.opts.jsonPretty); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+func (d *daemon) handleResults(w http.ResponseWriter, r *http.Request) {
+	latest, _, _ := d.snapshot()
+	if latest == nil {
+		http.Error(w, "no completed test yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := encodeJSON(w, latest, d.opts.jsonPretty); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// shareTimeout bounds the upload to the share endpoint
+const shareTimeout = 30 * time.Second
+
+// share publishes the result and returns where it can be found: a URL when a
+// share endpoint is configured, otherwise the path of a JSON file written to
+// the current directory
+func share(r *speedtest.Result, endpoint string) (string, error) {
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, r, true); err != nil {
+		return "", err
+	}
+	if endpoint == "" {
+		return shareFile(r, buf.Bytes())
+	}
+	return shareURL(endpoint, buf.Bytes())
+}
+
+// shareFile writes the result to a timestamped file
+func shareFile(r *speedtest.Result, data []byte) (string, error) {
+	path := fmt.Sprintf("cloudflare-speed-%s.json", r.Started.UTC().Format("20060102T150405Z"))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// shareURL POSTs the result to a paste service, which is expected to answer
+// with the URL of the paste either in a Location header or as the body
+func shareURL(endpoint string, data []byte) (string, error) {
+	client := &http.Client{Timeout: shareTimeout}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("share endpoint returned %s", resp.Status)
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		return location, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	url := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return "", fmt.Errorf("share endpoint did not return a URL")
+	}
+	return url, nil
+}
@@ -0,0 +1,23 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyRunSignal relays SIGUSR1, which asks the daemon for a run now, to c
+func notifyRunSignal(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1)
+}
+
+// processAlive reports whether a process with this pid exists. Signal 0
+// checks without delivering anything; EPERM means it exists but belongs to
+// another user.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
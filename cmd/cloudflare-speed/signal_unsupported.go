@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package main
+
+import "os"
+
+// notifyRunSignal does nothing since this platform has no SIGUSR1
+func notifyRunSignal(c chan<- os.Signal) {}
+
+// processAlive cannot tell whether a process exists on this platform, so it
+// assumes it does and leaves stale locks to be recognized by their age
+func processAlive(pid int) bool {
+	return true
+}
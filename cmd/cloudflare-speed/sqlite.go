@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+
+	// Registers the pure Go "sqlite" driver, so the build needs no cgo
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchemaVersion is stored in PRAGMA user_version so later releases
+// can tell which columns a database has and migrate it
+const sqliteSchemaVersion = 1
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY,
+	started TEXT NOT NULL,
+	version TEXT,
+	server_colo TEXT,
+	server_city TEXT,
+	ip TEXT,
+	location TEXT,
+	latency_ms REAL,
+	jitter_ms REAL,
+	ttfb_ms REAL,
+	download_mbps REAL,
+	upload_mbps REAL,
+	percentile REAL,
+	tls_version TEXT,
+	success_percent REAL,
+	result_json TEXT NOT NULL
+);`
+
+// writeSQLite appends r as a row of the runs table in the database at path,
+// creating the database and table if needed. The full result is kept as JSON
+// alongside the headline columns so any metric can be queried with
+// json_extract.
+func writeSQLite(path string, r *speedtest.Result) error {
+	var result bytes.Buffer
+	if err := encodeJSON(&result, r, false); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(sqliteSchema); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqliteSchemaVersion)); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO runs (started, version, server_colo, server_city, ip, location, latency_ms, jitter_ms, "+
+		"ttfb_ms, download_mbps, upload_mbps, percentile, tls_version, success_percent, result_json) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		r.Started.UTC().Format(time.RFC3339Nano),
+		r.Version,
+		r.ServerColo,
+		r.ServerCity,
+		r.IP,
+		r.Location,
+		sqlReal(r.Latency),
+		sqlReal(r.Jitter),
+		sqlReal(r.TTFB),
+		sqlReal(r.Download),
+		sqlReal(r.Upload),
+		sqlReal(r.Percentile),
+		r.TLSVersion,
+		sqlReal(r.Reliability.SuccessPercent),
+		strings.TrimSpace(result.String()),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqlReal returns f as a column value, or NULL when it is not finite since
+// SQL has no value for infinity or NaN
+func sqlReal(f float64) interface{} {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil
+	}
+	return f
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultStateFile is where --min-interval records the start of the last run
+var defaultStateFile = filepath.Join(os.TempDir(), "cloudflare-speed.state")
+
+// staleLockAge is how old a lock file must be to be taken over. The lock is
+// only held while the state file is read and written, so one this old was
+// left behind by a run that crashed.
+const staleLockAge = time.Minute
+
+// claimRun records now as the start of a run in the state file at path, or
+// returns an error if the previous run started less than minInterval ago
+func claimRun(path string, minInterval time.Duration, now time.Time) error {
+	// The lock serializes concurrent starts so two overlapping jobs cannot
+	// both read the same stale timestamp and go ahead
+	lockPath := path + ".lock"
+	if err := acquireLock(lockPath, now); err != nil {
+		return err
+	}
+	defer os.Remove(lockPath)
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		last, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+		if err == nil && now.Sub(last) < minInterval {
+			return fmt.Errorf("previous run started at %s, less than --min-interval %s ago", last.Format(time.RFC3339), minInterval)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return writeFileAtomic(path, []byte(now.Format(time.RFC3339Nano)+"\n"))
+}
+
+// acquireLock creates the lock file at path holding this process's PID. A
+// lock whose process has exited, or older than staleLockAge, was left behind
+// by a crashed run and is replaced once.
+func acquireLock(path string, now time.Time) error {
+	for attempt := 0; ; attempt++ {
+		lock, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = fmt.Fprintf(lock, "%d\n", os.Getpid())
+			if closeErr := lock.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		if attempt > 0 || !staleLock(path, now) {
+			return fmt.Errorf("another run is starting (remove %s if it is stale)", path)
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+}
+
+// staleLock reports whether the lock file at path was left behind by a run
+// that is no longer running. A lock without a PID yet is being created.
+func staleLock(path string, now time.Time) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+	if now.Sub(info.ModTime()) > staleLockAge {
+		return true
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return err == nil && !processAlive(pid)
+}
+
+// writeFileAtomic replaces path with data by renaming a temporary file over
+// it, so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
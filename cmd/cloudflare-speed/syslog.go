@@ -0,0 +1,61 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// syslogFacilities maps facility names to their syslog priorities
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+// checkSyslog reports a --syslog-facility that does not exist, before the
+// test runs rather than after
+func checkSyslog(opts options) error {
+	if _, ok := syslogFacilities[opts.syslogFacility]; !ok {
+		return fmt.Errorf("unknown --syslog-facility %q", opts.syslogFacility)
+	}
+	return nil
+}
+
+// writeSyslog sends the result to the local syslog daemon as a single
+// key=value message at info level
+func writeSyslog(r *speedtest.Result, opts options) error {
+	w, err := syslog.New(syslogFacilities[opts.syslogFacility]|syslog.LOG_INFO, opts.syslogTag)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	defer w.Close()
+	return w.Info(syslogMessage(r))
+}
+
+// syslogMessage formats the headline metrics as key=value pairs, which most
+// log pipelines can parse without configuration
+func syslogMessage(r *speedtest.Result) string {
+	return fmt.Sprintf("download_mbps=%.2f upload_mbps=%.2f latency_ms=%.2f jitter_ms=%.2f ttfb_ms=%.2f colo=%s",
+		r.Download, r.Upload, r.Latency, r.Jitter, r.TTFB, r.ServerColo)
+}
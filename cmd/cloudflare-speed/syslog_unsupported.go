@@ -0,0 +1,19 @@
+//go:build windows || plan9
+
+package main
+
+import (
+	"errors"
+
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// checkSyslog rejects --syslog, which writeSyslog cannot honor here
+func checkSyslog(opts options) error {
+	return errors.New("--syslog is not supported on this platform")
+}
+
+// writeSyslog is unavailable since this platform has no syslog
+func writeSyslog(r *speedtest.Result, opts options) error {
+	return errors.New("--syslog is not supported on this platform")
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/coleaeason/cloudflare-speed/speedtest"
+)
+
+// parseFormatTemplate parses a --format-template
+func parseFormatTemplate(text string) (*template.Template, error) {
+	return template.New("format").Option("missingkey=error").Parse(text)
+}
+
+// writeTemplate renders r with tmpl, ending the output with a newline
+func writeTemplate(w io.Writer, tmpl *template.Template, r *speedtest.Result) error {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, r); err != nil {
+		return err
+	}
+	out := b.String()
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
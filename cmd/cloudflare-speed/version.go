@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with
+// -ldflags "-X main.version=v1.2.3 -X main.commit=abc123 -X main.date=2024-01-01"
+// and otherwise filled in from the module build info where possible.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo describes the running binary
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// currentBuild returns the build info, preferring values set with -ldflags
+func currentBuild() buildInfo {
+	info := buildInfo{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.Date == "":
+				info.Date = setting.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// userAgent identifies the tool and its version in requests
+func userAgent() string {
+	return "cloudflare-speed/" + currentBuild().Version
+}
+
+// printVersion prints the build info
+func printVersion(opts options) error {
+	info := currentBuild()
+	if opts.json {
+		return writeJSON(info, opts.jsonPretty)
+	}
+	fmt.Printf("cloudflare-speed %s\n", info.Version)
+	if info.Commit != "" {
+		fmt.Printf("commit: %s\n", info.Commit)
+	}
+	if info.Date != "" {
+		fmt.Printf("built: %s\n", info.Date)
+	}
+	fmt.Printf("go: %s\n", info.GoVersion)
+	return nil
+}
@@ -0,0 +1,118 @@
+package analysis
+
+// Metrics are the measurements a connection is scored against
+type Metrics struct {
+	Download float64 // Mbps
+	Upload   float64 // Mbps
+	Latency  float64 // ms
+	Jitter   float64 // ms
+}
+
+// Score is the result of evaluating a single AIM experience
+type Score struct {
+	Points         int    `json:"points"`
+	Classification string `json:"classification"`
+}
+
+// AIMScores holds the Aggregated Internet Measurement score for each experience
+type AIMScores struct {
+	Streaming Score `json:"streaming"`
+	Gaming    Score `json:"gaming"`
+	VideoChat Score `json:"video_chat"`
+}
+
+// classifications are ordered worst to best and indexed by how many of an
+// experience's point thresholds were reached
+var classifications = []string{"bad", "poor", "average", "good", "great"}
+
+// AIM scores the suitability of a connection for streaming, gaming and video
+// chat. The rubric follows the structure of the speed.cloudflare.com AIM
+// scoring: each metric is converted to points and an experience's total
+// points are classified against its thresholds. Packet loss and loaded
+// latency are not measured, so they do not contribute.
+func AIM(m Metrics) AIMScores {
+	latency := latencyPoints(m.Latency)
+	jitter := jitterPoints(m.Jitter)
+	download := downloadPoints(m.Download)
+	upload := uploadPoints(m.Upload)
+
+	return AIMScores{
+		Streaming: classify(download+latency, []int{10, 20, 30, 40}),
+		Gaming:    classify(latency+jitter, []int{5, 10, 20, 25}),
+		VideoChat: classify(latency+jitter+upload, []int{10, 20, 30, 40}),
+	}
+}
+
+func classify(points int, thresholds []int) Score {
+	level := 0
+	for _, threshold := range thresholds {
+		if points >= threshold {
+			level++
+		}
+	}
+	return Score{Points: points, Classification: classifications[level]}
+}
+
+func latencyPoints(ms float64) int {
+	switch {
+	case ms <= 10:
+		return 20
+	case ms <= 20:
+		return 15
+	case ms <= 50:
+		return 10
+	case ms <= 100:
+		return 5
+	case ms <= 200:
+		return 0
+	default:
+		return -10
+	}
+}
+
+func jitterPoints(ms float64) int {
+	switch {
+	case ms <= 5:
+		return 10
+	case ms <= 10:
+		return 5
+	case ms <= 20:
+		return 0
+	case ms <= 50:
+		return -10
+	default:
+		return -20
+	}
+}
+
+func downloadPoints(mbps float64) int {
+	switch {
+	case mbps >= 100:
+		return 30
+	case mbps >= 25:
+		return 20
+	case mbps >= 10:
+		return 10
+	case mbps >= 5:
+		return 5
+	case mbps >= 1:
+		return 0
+	default:
+		return -20
+	}
+}
+
+func uploadPoints(mbps float64) int {
+	switch {
+	case mbps >= 50:
+		return 20
+	case mbps >= 10:
+		return 10
+	case mbps >= 3:
+		return 5
+	case mbps >= 1:
+		return 0
+	default:
+		return -20
+	}
+}
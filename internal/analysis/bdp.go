@@ -0,0 +1,12 @@
+package analysis
+
+// DefaultTCPWindow is the largest TCP receive window possible without the
+// window scaling option, in bytes
+const DefaultTCPWindow = 65535
+
+// BandwidthDelayProduct returns the number of bytes in flight needed to keep a
+// link of the given speed (Mbps) and round trip time (ms) full. A TCP receive
+// window smaller than this caps throughput below the link speed.
+func BandwidthDelayProduct(mbps, rttMs float64) float64 {
+	return mbps * 1e6 / 8 * rttMs / 1000
+}
@@ -0,0 +1,28 @@
+package analysis
+
+// bufferbloatGrades maps the latency increase under load, in ms, to a grade.
+// The thresholds follow the widely used Waveform bufferbloat test so grades
+// are comparable with it.
+var bufferbloatGrades = []struct {
+	below float64
+	grade string
+}{
+	{5, "A+"},
+	{30, "A"},
+	{60, "B"},
+	{200, "C"},
+	{400, "D"},
+}
+
+// BufferbloatGrade grades the increase in latency, in ms, seen while the
+// link is saturated compared to when it is idle. A larger increase means
+// larger buffers are filling up, which hurts calls and games while anything
+// else uses the connection.
+func BufferbloatGrade(increaseMs float64) string {
+	for _, g := range bufferbloatGrades {
+		if increaseMs < g.below {
+			return g.grade
+		}
+	}
+	return "F"
+}
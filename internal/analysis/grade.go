@@ -0,0 +1,72 @@
+package analysis
+
+import "math"
+
+// GradeComponents are the 0-100 sub-scores of each metric in a Grade
+type GradeComponents struct {
+	Download float64 `json:"download"`
+	Upload   float64 `json:"upload"`
+	Latency  float64 `json:"latency"`
+	Jitter   float64 `json:"jitter"`
+}
+
+// Grade summarizes a connection as a letter from A to F
+type Grade struct {
+	Letter     string          `json:"letter"`
+	Score      float64         `json:"score"`
+	Components GradeComponents `json:"components"`
+}
+
+// gradeWeights is how much each component contributes to the overall score.
+// Download and latency dominate everyday use; upload and jitter matter
+// mostly for calls and games.
+var gradeWeights = GradeComponents{Download: 0.35, Upload: 0.2, Latency: 0.3, Jitter: 0.15}
+
+// gradeLetters maps the lowest overall score earning each letter
+var gradeLetters = []struct {
+	min    float64
+	letter string
+}{
+	{90, "A"},
+	{80, "B"},
+	{65, "C"},
+	{50, "D"},
+}
+
+// ConnectionGrade scores each metric from 0 to 100 and grades their weighted
+// sum. Speeds are scored on a logarithmic scale, from 0 at 1 Mbps download
+// or 0.5 Mbps upload to 100 at 500 and 100 Mbps, since each doubling matters
+// about as much as the last. Latency scores 100 up to 10ms falling to 0 at
+// 200ms, and jitter 100 up to 2ms falling to 0 at 50ms.
+func ConnectionGrade(m Metrics) Grade {
+	c := GradeComponents{
+		Download: logScore(m.Download, 1, 500),
+		Upload:   logScore(m.Upload, 0.5, 100),
+		Latency:  linearScore(m.Latency, 10, 200),
+		Jitter:   linearScore(m.Jitter, 2, 50),
+	}
+	score := c.Download*gradeWeights.Download + c.Upload*gradeWeights.Upload +
+		c.Latency*gradeWeights.Latency + c.Jitter*gradeWeights.Jitter
+
+	letter := "F"
+	for _, g := range gradeLetters {
+		if score >= g.min {
+			letter = g.letter
+			break
+		}
+	}
+	return Grade{Letter: letter, Score: score, Components: c}
+}
+
+// logScore scores a value where higher is better, 0 at low and 100 at high
+func logScore(value, low, high float64) float64 {
+	if value <= low {
+		return 0
+	}
+	return math.Min(100, 100*math.Log(value/low)/math.Log(high/low))
+}
+
+// linearScore scores a value where lower is better, 100 at best and 0 at worst
+func linearScore(value, best, worst float64) float64 {
+	return math.Max(0, math.Min(100, 100*(worst-value)/(worst-best)))
+}
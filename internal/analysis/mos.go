@@ -0,0 +1,21 @@
+package analysis
+
+// MOS estimates the mean opinion score of a voice call, from 1 (bad) to about
+// 4.4 (a flawless narrowband call), with a simplified ITU-T G.107 E-model.
+// Jitter counts twice, for the jitter buffer that smooths it out, 10ms is
+// added for the codec, and each percent of loss costs 2.5 points of R.
+func MOS(latencyMs, jitterMs, lossPercent float64) float64 {
+	delay := latencyMs + 2*jitterMs + 10
+	r := 93.2 - delay/40
+	if delay >= 160 {
+		r = 93.2 - (delay-120)/10
+	}
+	r -= 2.5 * lossPercent
+
+	// The polynomial dips just below 1 for the lowest R factors
+	mos := 1 + 0.035*r + 7e-6*r*(r-60)*(100-r)
+	if r <= 0 || mos < 1 {
+		return 1
+	}
+	return mos
+}
@@ -0,0 +1,36 @@
+package analysis
+
+import "testing"
+
+func TestMOS(t *testing.T) {
+	tests := []struct {
+		name                  string
+		latency, jitter, loss float64
+		min, max              float64
+	}{
+		{name: "ideal", min: 4.39, max: 4.41},
+		{name: "typical broadband", latency: 20, jitter: 2, min: 4.35, max: 4.4},
+		{name: "long delay", latency: 300, jitter: 20, min: 3.55, max: 3.65},
+		{name: "loss", latency: 20, jitter: 2, loss: 10, min: 3.42, max: 3.52},
+		{name: "unusable", latency: 1000, jitter: 100, loss: 50, min: 1, max: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MOS(tt.latency, tt.jitter, tt.loss)
+			if got < tt.min || got > tt.max {
+				t.Errorf("MOS(%v, %v, %v) = %v, want %v to %v", tt.latency, tt.jitter, tt.loss, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestMOSLossLowersScore(t *testing.T) {
+	prev := MOS(20, 2, 0)
+	for loss := 1.0; loss <= 40; loss++ {
+		got := MOS(20, 2, loss)
+		if got > prev {
+			t.Fatalf("MOS rose from %v to %v at %v%% loss", prev, got, loss)
+		}
+		prev = got
+	}
+}
@@ -0,0 +1,23 @@
+package analysis
+
+// Stability labels how consistent a set of speed samples was
+type Stability struct {
+	// CV is the coefficient of variation of the samples, their standard
+	// deviation relative to their mean
+	CV    float64 `json:"cv"`
+	Label string  `json:"label"`
+}
+
+// StabilityOf labels a coefficient of variation. Samples within about 10% of
+// each other are stable; beyond 25% the headline figure depends heavily on
+// which samples happened to be taken and should not be trusted on its own.
+func StabilityOf(cv float64) Stability {
+	label := "unstable"
+	switch {
+	case cv < 0.1:
+		label = "stable"
+	case cv < 0.25:
+		label = "variable"
+	}
+	return Stability{CV: cv, Label: label}
+}
@@ -0,0 +1,23 @@
+package analysis
+
+import "testing"
+
+func TestStabilityOf(t *testing.T) {
+	tests := []struct {
+		cv    float64
+		label string
+	}{
+		{0, "stable"},
+		{0.099, "stable"},
+		{0.1, "variable"},
+		{0.249, "variable"},
+		{0.25, "unstable"},
+		{1.5, "unstable"},
+	}
+	for _, tt := range tests {
+		got := StabilityOf(tt.cv)
+		if got.Label != tt.label || got.CV != tt.cv {
+			t.Errorf("StabilityOf(%v) = %+v, want label %q", tt.cv, got, tt.label)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Package iocount provides a reader that counts the bytes passing through it
+package iocount
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// CountingReader counts the bytes read from an underlying reader. Count may
+// be called from another goroutine while reads are in progress.
+type CountingReader struct {
+	r        io.Reader
+	n        int64 // accessed atomically
+	progress func(n int)
+}
+
+// NewCountingReader returns a CountingReader reading from r. If progress is
+// not nil it is called with the size of every read that returns data.
+func NewCountingReader(r io.Reader, progress func(n int)) *CountingReader {
+	return &CountingReader{r: r, progress: progress}
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		atomic.AddInt64(&c.n, int64(n))
+		if c.progress != nil {
+			c.progress(n)
+		}
+	}
+	return n, err
+}
+
+// Count returns the number of bytes read so far
+func (c *CountingReader) Count() int64 {
+	return atomic.LoadInt64(&c.n)
+}
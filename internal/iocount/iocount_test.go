@@ -0,0 +1,91 @@
+package iocount
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCountingReaderShortReads(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 1000)
+	var progress []int
+	r := NewCountingReader(iotest.OneByteReader(bytes.NewReader(data)), func(n int) {
+		progress = append(progress, n)
+	})
+
+	buf := make([]byte, 64)
+	n, err := r.Read(buf)
+	if n != 1 || err != nil {
+		t.Fatalf("Read = %d, %v, want 1, nil", n, err)
+	}
+	if r.Count() != 1 {
+		t.Errorf("Count = %d after a short read, want 1", r.Count())
+	}
+
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Count() != int64(len(data)) {
+		t.Errorf("Count = %d, want %d", r.Count(), len(data))
+	}
+	if len(progress) != len(data) {
+		t.Errorf("progress called %d times, want %d", len(progress), len(data))
+	}
+}
+
+func TestCountingReaderEOF(t *testing.T) {
+	r := NewCountingReader(iotest.DataErrReader(bytes.NewReader([]byte("hello"))), nil)
+
+	n, err := r.Read(make([]byte, 64))
+	if n != 5 || err != io.EOF {
+		t.Fatalf("Read = %d, %v, want 5, EOF", n, err)
+	}
+	if n, err := r.Read(make([]byte, 64)); n != 0 || err != io.EOF {
+		t.Fatalf("Read after EOF = %d, %v, want 0, EOF", n, err)
+	}
+	if r.Count() != 5 {
+		t.Errorf("Count = %d, want 5", r.Count())
+	}
+}
+
+func TestCountingReaderError(t *testing.T) {
+	errBroken := errors.New("connection reset")
+	var calls int
+	r := NewCountingReader(io.MultiReader(bytes.NewReader([]byte("abc")), iotest.ErrReader(errBroken)), func(int) {
+		calls++
+	})
+
+	data, err := io.ReadAll(r)
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("err = %v, want %v", err, errBroken)
+	}
+	if string(data) != "abc" || r.Count() != 3 {
+		t.Errorf("read %q with Count %d, want \"abc\" with 3", data, r.Count())
+	}
+	if calls != 1 {
+		t.Errorf("progress called %d times, want only for the read that returned data", calls)
+	}
+}
+
+func TestCountingReaderConcurrentCount(t *testing.T) {
+	r := NewCountingReader(bytes.NewReader(make([]byte, 1<<20)), nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var last int64
+		for last < 1<<20 {
+			n := r.Count()
+			if n < last {
+				t.Errorf("Count went from %d to %d", last, n)
+				return
+			}
+			last = n
+		}
+	}()
+	if _, err := io.Copy(io.Discard, iotest.HalfReader(r)); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
@@ -0,0 +1,43 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// printer formats numbers for the selected locale. When nil FormatFloat
+// matches the C locale: a decimal point and no grouping.
+var printer *message.Printer
+
+// SetLocale selects the number format used for human readable output from a
+// locale name such as "de_DE.UTF-8". It returns false for unknown locales,
+// leaving the format unchanged.
+func SetLocale(name string) bool {
+	// POSIX names add an encoding and modifier to a BCP 47 like tag
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "C" || name == "POSIX" {
+		printer = nil
+		return true
+	}
+	tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+	if err != nil {
+		return false
+	}
+	printer = message.NewPrinter(tag)
+	return true
+}
+
+// FormatFloat formats value with the given number of decimals using the
+// selected locale's separators
+func FormatFloat(value float64, precision int) string {
+	if printer == nil {
+		return strconv.FormatFloat(value, 'f', precision, 64)
+	}
+	return printer.Sprint(number.Decimal(value, number.MinFractionDigits(precision), number.MaxFractionDigits(precision)))
+}
@@ -0,0 +1,34 @@
+package log
+
+import "testing"
+
+func TestFormatFloatLocale(t *testing.T) {
+	defer SetLocale("C")
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"C", "-1234567.89"},
+		{"de_DE.UTF-8", "-1.234.567,89"},
+		{"C.UTF-8", "-1234567.89"},
+		{"en_US", "-1,234,567.89"},
+		{"de-CH", "-1’234’567.89"},
+		{"hi_IN@latin", "-12,34,567.89"},
+	}
+	for _, tt := range tests {
+		if !SetLocale(tt.locale) {
+			t.Fatalf("SetLocale(%q) = false", tt.locale)
+		}
+		if got := FormatFloat(-1234567.891, 2); got != tt.want {
+			t.Errorf("%s: FormatFloat = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+
+	SetLocale("de_DE")
+	if SetLocale("xx_YY") {
+		t.Error("SetLocale accepted an unknown locale")
+	}
+	if got := FormatFloat(1.5, 0); got != "2" {
+		t.Errorf("FormatFloat(1.5, 0) = %q after a rejected locale, want \"2\"", got)
+	}
+}
@@ -6,35 +6,44 @@ import (
 	"github.com/fatih/color"
 )
 
-// Available text styles
-var (
-	Bold    = color.New(color.Bold).SprintFunc()
-	Blue    = color.New(color.FgBlue).SprintFunc()
-	Green   = color.New(color.FgGreen).SprintFunc()
-	Yellow  = color.New(color.FgYellow).SprintFunc()
-	Magenta = color.New(color.FgMagenta).SprintFunc()
-	Red     = color.New(color.FgRed).SprintFunc()
-)
+// Bold styles labels. Values are styled by role, see Info, Metric, Good,
+// Warn and Bad.
+var Bold = color.New(color.Bold).SprintFunc()
+
+// plain disables all styling so output contains no escape sequences
+var plain bool
+
+// SetPlain toggles plain output, which prints simple "key: value" lines without bold or color
+func SetPlain(enabled bool) {
+	plain = enabled
+}
+
+// styled joins a label and value, applying bold and the given colorFunc unless plain output is enabled
+func styled(label, value string, colorFunc func(...interface{}) string) string {
+	if plain {
+		return label + value
+	}
+	return Bold(label, colorFunc(value))
+}
 
 // Print formats and prints a message with the given colorFunc for highlighted text
 func Print(prefix, format string, colorFunc func(...interface{}) string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Println(Bold(prefix, colorFunc(msg)))
+	fmt.Println(styled(prefix, msg, colorFunc))
 }
 
 // PrintPair prints a key-value pair with the key in bold and value in the specified color
 func PrintPair(key, value string, colorFunc func(...interface{}) string) {
-	fmt.Println(Bold(key+": ", colorFunc(value)))
+	fmt.Println(styled(key+": ", value, colorFunc))
 }
 
 // PrintValue prints a simple value with the given color
 func PrintValue(label string, value interface{}, colorFunc func(...interface{}) string) {
-	fmt.Println(Bold(label+": ", colorFunc(fmt.Sprintf("%v", value))))
+	fmt.Println(styled(label+": ", fmt.Sprintf("%v", value), colorFunc))
 }
 
-// PrintFloat prints a float value with the given precision and unit
+// PrintFloat prints a float value with the given precision and unit, formatted for the selected locale
 func PrintFloat(label string, value float64, precision int, unit string, colorFunc func(...interface{}) string) {
-	format := fmt.Sprintf("%%.%df %s", precision, unit)
-	formatted := fmt.Sprintf(format, value)
-	fmt.Println(Bold(label+": ", colorFunc(formatted)))
+	formatted := FormatFloat(value, precision) + " " + unit
+	fmt.Println(styled(label+": ", formatted, colorFunc))
 }
@@ -0,0 +1,69 @@
+package log
+
+import "github.com/fatih/color"
+
+// Semantic text styles. Output picks the role of a value rather than a color
+// so that SetColorScheme can remap every color in one place.
+var (
+	Info   func(...interface{}) string // descriptive values such as locations and settings
+	Metric func(...interface{}) string // measured latencies and rates
+	Good   func(...interface{}) string // headline speeds and passing checks
+	Warn   func(...interface{}) string // values worth a second look
+	Bad    func(...interface{}) string // failures and warnings
+)
+
+// colorScheme assigns a style to each role
+type colorScheme struct {
+	info, metric, good, warn, bad *color.Color
+}
+
+// colorSchemes are the palettes selectable with SetColorScheme. The
+// deuteranopia palette avoids telling good from bad by red and green alone,
+// and monochrome relies on weight and underlining only.
+var colorSchemes = map[string]colorScheme{
+	"default": {
+		info:   color.New(color.FgBlue),
+		metric: color.New(color.FgMagenta),
+		good:   color.New(color.FgGreen),
+		warn:   color.New(color.FgYellow),
+		bad:    color.New(color.FgRed),
+	},
+	"deuteranopia": {
+		info:   color.New(color.FgCyan),
+		metric: color.New(color.FgWhite),
+		good:   color.New(color.FgBlue),
+		warn:   color.New(color.FgMagenta),
+		bad:    color.New(color.FgHiYellow, color.Underline),
+	},
+	"monochrome": {
+		info:   color.New(color.Reset),
+		metric: color.New(color.Reset),
+		good:   color.New(color.Reset),
+		warn:   color.New(color.Italic),
+		bad:    color.New(color.Underline),
+	},
+}
+
+func init() {
+	SetColorScheme("default")
+}
+
+// ColorSchemes lists the names accepted by SetColorScheme
+func ColorSchemes() []string {
+	return []string{"default", "deuteranopia", "monochrome"}
+}
+
+// SetColorScheme selects the palette used for each role, returning false if
+// the name is not one of ColorSchemes
+func SetColorScheme(name string) bool {
+	scheme, ok := colorSchemes[name]
+	if !ok {
+		return false
+	}
+	Info = scheme.info.SprintFunc()
+	Metric = scheme.metric.SprintFunc()
+	Good = scheme.good.SprintFunc()
+	Warn = scheme.warn.SprintFunc()
+	Bad = scheme.bad.SprintFunc()
+	return true
+}
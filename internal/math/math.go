@@ -1,6 +1,45 @@
 package math
 
-import "sort"
+import (
+	stdmath "math"
+	"sort"
+)
+
+// Stats holds summary statistics for a slice of float64 values
+type Stats struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	Jitter float64 `json:"jitter"`
+	StdDev float64 `json:"stddev"`
+}
+
+// Summarize calculates the summary statistics of a slice of float64 values
+func Summarize(values []float64) Stats {
+	if len(values) == 0 {
+		return Stats{}
+	}
+	stats := Stats{
+		Count:  len(values),
+		Min:    values[0],
+		Max:    values[0],
+		Mean:   Average(values),
+		Median: Median(values),
+		Jitter: Jitter(values),
+	}
+	for _, v := range values {
+		if v < stats.Min {
+			stats.Min = v
+		}
+		if v > stats.Max {
+			stats.Max = v
+		}
+	}
+	stats.StdDev = stdmath.Sqrt(stats.Jitter)
+	return stats
+}
 
 // Average calculates the arithmetic mean of a slice of float64 values
 func Average(values []float64) float64 {
@@ -42,6 +81,126 @@ func Jitter(values []float64) float64 {
 	return (sum / float64(len(values)-1))
 }
 
+// ConsecutiveJitter calculates the mean absolute difference between
+// consecutive values. Unlike Jitter, which measures how widely the values are
+// spread, this measures how much each value changes from the one before it,
+// as real-time applications experience it, so it depends on their order.
+func ConsecutiveJitter(values []float64) float64 {
+	if len(values) <= 1 {
+		return 0
+	}
+	var sum float64
+	for i := 1; i < len(values); i++ {
+		sum += stdmath.Abs(values[i] - values[i-1])
+	}
+	return sum / float64(len(values)-1)
+}
+
+// CoefficientOfVariation returns the sample standard deviation of values
+// divided by their mean, a measure of spread that does not depend on scale.
+// It is 0 for fewer than two values or a zero mean.
+func CoefficientOfVariation(values []float64) float64 {
+	mean := Average(values)
+	if len(values) <= 1 || mean == 0 {
+		return 0
+	}
+	return stdmath.Sqrt(Jitter(values)) / stdmath.Abs(mean)
+}
+
+// tCritical95 holds the two-sided 95% critical values of Student's t
+// distribution for 1 to 30 degrees of freedom
+var tCritical95 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// ConfidenceInterval returns the half width of the 95% confidence interval
+// of the mean of values. Student's t distribution is used so that a few
+// samples are not overconfident; beyond 30 degrees of freedom it is close
+// enough to the normal distribution's 1.96. It is 0 for fewer than two values.
+func ConfidenceInterval(values []float64) float64 {
+	n := len(values)
+	if n <= 1 {
+		return 0
+	}
+	t := 1.96
+	if n-1 <= len(tCritical95) {
+		t = tCritical95[n-2]
+	}
+	return t * stdmath.Sqrt(Jitter(values)/float64(n))
+}
+
+// EWMA folds sample into the exponentially weighted moving average prev.
+// Alpha (0 to 1) is the weight given to the new sample; smaller values
+// smooth more but follow changes more slowly.
+func EWMA(prev, sample, alpha float64) float64 {
+	return alpha*sample + (1-alpha)*prev
+}
+
+// Mode rounds each value to the nearest multiple of bucket and returns the
+// most frequent rounded value. When several are equally frequent the lowest
+// is returned.
+func Mode(values []float64, bucket float64) float64 {
+	if len(values) == 0 || bucket <= 0 {
+		return 0
+	}
+	counts := make(map[float64]int)
+	for _, v := range values {
+		counts[stdmath.Round(v/bucket)*bucket]++
+	}
+	var mode float64
+	best := 0
+	for value, count := range counts {
+		if count > best || (count == best && value < mode) {
+			mode, best = value, count
+		}
+	}
+	return mode
+}
+
+// Histogram divides the range of values into bins of equal width and counts
+// the values in each. It returns the bins+1 edges, bin i covering edges[i]
+// up to but excluding edges[i+1], except the last bin which includes the
+// maximum. When every value is equal the range is widened by 0.5 either side
+// so they fall in the middle. Both are nil for no values or bins below 1.
+func Histogram(values []float64, bins int) ([]float64, []int) {
+	if len(values) == 0 || bins < 1 {
+		return nil, nil
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = stdmath.Min(lo, v)
+		hi = stdmath.Max(hi, v)
+	}
+	if lo == hi {
+		lo, hi = lo-0.5, hi+0.5
+	}
+
+	width := (hi - lo) / float64(bins)
+	edges := make([]float64, bins+1)
+	for i := range edges {
+		edges[i] = lo + float64(i)*width
+	}
+	edges[bins] = hi
+
+	counts := make([]int, bins)
+	for _, v := range values {
+		// Rounding can put a value on an edge into the neighbouring bin
+		bin := int((v - lo) / width)
+		if bin >= bins {
+			bin = bins - 1
+		}
+		if bin+1 < bins && v >= edges[bin+1] {
+			bin++
+		} else if bin > 0 && v < edges[bin] {
+			bin--
+		}
+		counts[bin]++
+	}
+	return edges, counts
+}
+
 // Quartile finds the value at a specified quartile in a slice of float64 values
 func Quartile(values []float64, q float64) float64 {
 	if len(values) == 0 {
@@ -56,3 +215,50 @@ func Quartile(values []float64, q float64) float64 {
 	}
 	return sorted[pos]
 }
+
+// PercentileMethod selects how Percentile picks a value between samples
+type PercentileMethod string
+
+const (
+	// NearestRank returns the sample at index floor(n*q) of the sorted
+	// values, the method used by Quartile
+	NearestRank PercentileMethod = "nearest-rank"
+
+	// Interpolated linearly interpolates between the two samples either side
+	// of rank (n-1)*q, as spreadsheets and numpy do by default. It can return
+	// a value that was never observed.
+	Interpolated PercentileMethod = "interpolated"
+)
+
+// Percentile finds the value at quantile q (0 to 1) of a slice of float64
+// values using the given method
+func Percentile(values []float64, q float64, method PercentileMethod) float64 {
+	if method == Interpolated {
+		return InterpolatedPercentile(values, q)
+	}
+	return Quartile(values, q)
+}
+
+// InterpolatedPercentile finds the value at quantile q (0 to 1) of a slice of
+// float64 values, interpolating linearly between the closest ranks
+func InterpolatedPercentile(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	if q <= 0 {
+		return sorted[0]
+	}
+	if q >= 1 {
+		return sorted[len(sorted)-1]
+	}
+	rank := q * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	frac := rank - float64(lower)
+	return sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+}
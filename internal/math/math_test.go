@@ -0,0 +1,299 @@
+package math
+
+import (
+	stdmath "math"
+	"reflect"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		bins   int
+		edges  []float64
+		counts []int
+	}{
+		{
+			// Old Faithful eruption durations in minutes, the first 20 of the classic dataset
+			name:   "known dataset",
+			values: []float64{3.6, 1.8, 3.333, 2.283, 4.533, 2.883, 4.7, 3.6, 1.95, 4.35, 1.833, 3.917, 4.2, 1.75, 4.7, 2.167, 1.75, 4.8, 1.6, 4.25},
+			bins:   4,
+			edges:  []float64{1.6, 2.4, 3.2, 4.0, 4.8},
+			counts: []int{8, 1, 4, 7},
+		},
+		{
+			name:   "values on edges",
+			values: []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			bins:   5,
+			edges:  []float64{0, 2, 4, 6, 8, 10},
+			counts: []int{2, 2, 2, 2, 3},
+		},
+		{
+			name:   "tenths",
+			values: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7},
+			bins:   6,
+			counts: []int{1, 1, 1, 1, 1, 2},
+		},
+		{
+			name:   "all equal",
+			values: []float64{5, 5, 5},
+			bins:   2,
+			edges:  []float64{4.5, 5, 5.5},
+			counts: []int{0, 3},
+		},
+		{
+			name:   "single value",
+			values: []float64{7},
+			bins:   1,
+			edges:  []float64{6.5, 7.5},
+			counts: []int{1},
+		},
+		{
+			name:   "negative values",
+			values: []float64{-4, -1, 0, 2},
+			bins:   3,
+			edges:  []float64{-4, -2, 0, 2},
+			counts: []int{1, 1, 2},
+		},
+		{name: "no values", bins: 3},
+		{name: "no bins", values: []float64{1, 2}, bins: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edges, counts := Histogram(tt.values, tt.bins)
+			if !reflect.DeepEqual(counts, tt.counts) {
+				t.Errorf("counts = %v, want %v", counts, tt.counts)
+			}
+			if tt.edges != nil && !approxEqual(edges, tt.edges) {
+				t.Errorf("edges = %v, want %v", edges, tt.edges)
+			}
+			if counts != nil && len(edges) != tt.bins+1 {
+				t.Errorf("got %d edges, want %d", len(edges), tt.bins+1)
+			}
+			total := 0
+			for _, c := range counts {
+				total += c
+			}
+			if total != len(tt.values) && tt.bins > 0 {
+				t.Errorf("counted %d values, want %d", total, len(tt.values))
+			}
+		})
+	}
+}
+
+func TestHistogramValueOnEdge(t *testing.T) {
+	// (edges[6]-lo)/width rounds down to 5 for this range
+	edges, _ := Histogram([]float64{8.994, 17.694}, 9)
+	_, counts := Histogram([]float64{8.994, edges[6], 17.694}, 9)
+	if counts[6] != 1 {
+		t.Errorf("counts = %v, want the value on edges[6] in bin 6", counts)
+	}
+}
+
+func approxEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if d := a[i] - b[i]; d > 1e-9 || d < -1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   Stats
+	}{
+		{name: "no values", want: Stats{}},
+		{
+			name:   "single value",
+			values: []float64{5},
+			want:   Stats{Count: 1, Min: 5, Max: 5, Mean: 5, Median: 5},
+		},
+		{
+			name:   "odd count",
+			values: []float64{3, 1, 2},
+			want:   Stats{Count: 3, Min: 1, Max: 3, Mean: 2, Median: 2, Jitter: 1, StdDev: 1},
+		},
+		{
+			name:   "even count",
+			values: []float64{4, 1, 3, 2},
+			want:   Stats{Count: 4, Min: 1, Max: 4, Mean: 2.5, Median: 2.5, Jitter: 5.0 / 3, StdDev: stdmath.Sqrt(5.0 / 3)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Summarize(tt.values)
+			if got.Count != tt.want.Count || !approxEqual(
+				[]float64{got.Min, got.Max, got.Mean, got.Median, got.Jitter, got.StdDev},
+				[]float64{tt.want.Min, tt.want.Max, tt.want.Mean, tt.want.Median, tt.want.Jitter, tt.want.StdDev},
+			) {
+				t.Errorf("Summarize(%v) = %+v, want %+v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeDoesNotSort(t *testing.T) {
+	values := []float64{3, 1, 2}
+	Summarize(values)
+	if !reflect.DeepEqual(values, []float64{3, 1, 2}) {
+		t.Errorf("values reordered to %v", values)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	// 1 to 10, shuffled so the functions have to sort a copy
+	values := []float64{7, 3, 10, 1, 5, 9, 2, 8, 4, 6}
+	tests := []struct {
+		q                         float64
+		nearestRank, interpolated float64
+	}{
+		{q: 0, nearestRank: 1, interpolated: 1},
+		{q: 0.5, nearestRank: 6, interpolated: 5.5},
+		{q: 0.9, nearestRank: 10, interpolated: 9.1},
+		{q: 1, nearestRank: 10, interpolated: 10},
+	}
+	for _, tt := range tests {
+		if got := Percentile(values, tt.q, NearestRank); got != tt.nearestRank {
+			t.Errorf("nearest rank p%v = %v, want %v", tt.q*100, got, tt.nearestRank)
+		}
+		if got := Percentile(values, tt.q, Interpolated); !approxEqual([]float64{got}, []float64{tt.interpolated}) {
+			t.Errorf("interpolated p%v = %v, want %v", tt.q*100, got, tt.interpolated)
+		}
+	}
+	if values[0] != 7 {
+		t.Errorf("values were sorted in place: %v", values)
+	}
+}
+
+func TestPercentileEdgeCases(t *testing.T) {
+	for _, method := range []PercentileMethod{NearestRank, Interpolated} {
+		if got := Percentile(nil, 0.9, method); got != 0 {
+			t.Errorf("%s of no values = %v, want 0", method, got)
+		}
+		if got := Percentile([]float64{42}, 0.9, method); got != 42 {
+			t.Errorf("%s of a single value = %v, want 42", method, got)
+		}
+	}
+	if got := InterpolatedPercentile([]float64{1, 2}, -0.5); got != 1 {
+		t.Errorf("quantile below 0 = %v, want the minimum", got)
+	}
+	if got := InterpolatedPercentile([]float64{1, 2}, 1.5); got != 2 {
+		t.Errorf("quantile above 1 = %v, want the maximum", got)
+	}
+}
+
+func TestConsecutiveJitter(t *testing.T) {
+	// Steadily rising latency changes little from probe to probe but spreads
+	// widely, so the two jitter measures disagree
+	ordered := []float64{10, 11, 12, 13, 14, 15}
+	if got := ConsecutiveJitter(ordered); got != 1 {
+		t.Errorf("ConsecutiveJitter(%v) = %v, want 1", ordered, got)
+	}
+	if spread := Jitter(ordered); spread != 3.5 {
+		t.Errorf("Jitter(%v) = %v, want 3.5", ordered, spread)
+	}
+
+	// The same values alternating change a lot from probe to probe
+	alternating := []float64{10, 15, 11, 14, 12, 13}
+	if got := ConsecutiveJitter(alternating); got != 3 {
+		t.Errorf("ConsecutiveJitter(%v) = %v, want 3", alternating, got)
+	}
+
+	for _, values := range [][]float64{nil, {42}} {
+		if got := ConsecutiveJitter(values); got != 0 {
+			t.Errorf("ConsecutiveJitter(%v) = %v, want 0", values, got)
+		}
+	}
+}
+
+func TestMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		bucket float64
+		want   float64
+	}{
+		{"single mode", []float64{3, 1, 3, 2, 3}, 1, 3},
+		{"tie picks the lowest", []float64{5, 2, 5, 2, 9}, 1, 2},
+		{"rounds to the nearest bucket", []float64{9.8, 10.2, 10.4, 20}, 1, 10},
+		{"wide buckets", []float64{12, 13, 14}, 5, 15},
+		{"fractional buckets", []float64{1.2, 1.1, 3.4}, 0.5, 1},
+		{"no values", nil, 1, 0},
+		{"zero bucket", []float64{1, 1, 2}, 0, 0},
+		{"negative bucket", []float64{1, 1, 2}, -1, 0},
+	}
+	for _, tt := range tests {
+		if got := Mode(tt.values, tt.bucket); got != tt.want {
+			t.Errorf("%s: Mode(%v, %v) = %v, want %v", tt.name, tt.values, tt.bucket, got, tt.want)
+		}
+	}
+}
+
+func TestEWMA(t *testing.T) {
+	tests := []struct {
+		name                string
+		prev, sample, alpha float64
+		want                float64
+	}{
+		// Callers seed the average with the first sample, leaving it unchanged
+		{"first value", 40, 40, 0.3, 40},
+		{"alpha 0 ignores the sample", 40, 100, 0, 40},
+		{"alpha 1 follows the sample", 40, 100, 1, 100},
+		{"weighted", 40, 100, 0.25, 55},
+	}
+	for _, tt := range tests {
+		if got := EWMA(tt.prev, tt.sample, tt.alpha); got != tt.want {
+			t.Errorf("%s: EWMA(%v, %v, %v) = %v, want %v", tt.name, tt.prev, tt.sample, tt.alpha, got, tt.want)
+		}
+	}
+}
+
+func TestCoefficientOfVariation(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"no values", nil, 0},
+		{"single value", []float64{42}, 0},
+		{"identical samples", []float64{50, 50, 50, 50}, 0},
+		{"zero mean", []float64{-1, 1}, 0},
+		// Standard deviation 2 around a mean of 10
+		{"spread", []float64{8, 10, 12}, 0.2},
+		{"negative mean", []float64{-8, -10, -12}, 0.2},
+	}
+	for _, tt := range tests {
+		if got := CoefficientOfVariation(tt.values); stdmath.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: CoefficientOfVariation(%v) = %v, want %v", tt.name, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestConfidenceInterval(t *testing.T) {
+	// Two degrees of freedom use t = 4.303, with a sample variance of 4
+	if got, want := ConfidenceInterval([]float64{8, 10, 12}), 4.303*stdmath.Sqrt(4.0/3); stdmath.Abs(got-want) > 1e-9 {
+		t.Errorf("three samples: got %v, want %v", got, want)
+	}
+
+	// Beyond 30 degrees of freedom the normal distribution's 1.96 is used
+	many := make([]float64, 40)
+	for i := range many {
+		many[i] = float64(9 + 2*(i%2))
+	}
+	if got, want := ConfidenceInterval(many), 1.96/stdmath.Sqrt(39); stdmath.Abs(got-want) > 1e-9 {
+		t.Errorf("40 samples: got %v, want %v", got, want)
+	}
+
+	for _, values := range [][]float64{nil, {42}} {
+		if got := ConfidenceInterval(values); got != 0 {
+			t.Errorf("ConfidenceInterval(%v) = %v, want 0", values, got)
+		}
+	}
+}
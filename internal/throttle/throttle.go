@@ -0,0 +1,88 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket limiting throughput to a fixed number of bytes per
+// second. A single Bucket may be shared by several readers so that their
+// combined throughput stays under the limit.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a Bucket allowing bytesPerSecond, with a burst of a tenth
+// of a second's worth of data
+func NewBucket(bytesPerSecond float64) *Bucket {
+	burst := bytesPerSecond / 10
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{
+		rate:   bytesPerSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// take removes n tokens, waiting until the bucket has refilled enough to
+// cover them or ctx is done
+func (b *Bucket) take(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	deficit := -b.tokens
+	b.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Reader is an io.Reader whose throughput is limited by a Bucket
+type Reader struct {
+	ctx    context.Context
+	r      io.Reader
+	bucket *Bucket
+}
+
+// NewReader returns a Reader reading from r at no more than the bucket's
+// rate. A read waiting for the bucket returns ctx's error once it is done.
+func NewReader(ctx context.Context, r io.Reader, bucket *Bucket) *Reader {
+	return &Reader{ctx: ctx, r: r, bucket: bucket}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	// Keep reads within the burst size so the limit is smooth
+	if max := int(r.bucket.burst); len(p) > max {
+		p = p[:max]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.bucket.take(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,41 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReaderRate(t *testing.T) {
+	const rate = 100_000 // bytes per second
+	r := NewReader(context.Background(), bytes.NewReader(make([]byte, 30_000)), NewBucket(rate))
+
+	started := time.Now()
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	// The first tenth of a second's worth is the initial burst
+	want := time.Duration(float64(30_000-rate/10) / rate * float64(time.Second))
+	if elapsed := time.Since(started); elapsed < want*9/10 || elapsed > want*2 {
+		t.Errorf("read 30kB in %s, want about %s", elapsed, want)
+	}
+}
+
+func TestReaderCancel(t *testing.T) {
+	// Reading 1000 bytes at 10 bytes per second would take 100s
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewReader(ctx, bytes.NewReader(make([]byte, 1000)), NewBucket(10))
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	started := time.Now()
+	_, err := io.Copy(io.Discard, r)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("cancelled read returned after %s", elapsed)
+	}
+}
@@ -0,0 +1,50 @@
+package speedtest
+
+import (
+	"context"
+	"sort"
+)
+
+// AddressLatency is the median latency measured to a single resolved address
+type AddressLatency struct {
+	IP      string  `json:"ip"`
+	Latency float64 `json:"latency_ms,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// ProbeAddresses resolves hostname and measures latency to each A/AAAA record
+// directly, returning the reachable addresses fastest first
+func (c *Client) ProbeAddresses(ctx context.Context, hostname string) ([]AddressLatency, error) {
+	addrs, err := c.resolver.LookupIPAddr(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []AddressLatency
+	for _, addr := range addrs {
+		ipClient := *c
+		ipClient.dialAddr = addr.IP.String()
+		// The shared transport dials through c, so connections to this
+		// address need a transport of their own
+		ipClient.transport = ipClient.newTransport()
+
+		result := AddressLatency{IP: ipClient.dialAddr}
+		ping, err := ipClient.measureLatency(ctx, latencyProbes)
+		ipClient.transport.CloseIdleConnections()
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Latency = ping.latency.Median
+		}
+		results = append(results, result)
+	}
+
+	// Unreachable addresses sort last
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Error == "") != (results[j].Error == "") {
+			return results[i].Error == ""
+		}
+		return results[i].Latency < results[j].Latency
+	})
+	return results, nil
+}
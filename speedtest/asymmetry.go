@@ -0,0 +1,67 @@
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+// asymmetryPayload is the size in bytes of each asymmetry probe in either direction
+const asymmetryPayload = 1000
+
+// AsymmetryResult compares the latency of small downloads and small uploads
+type AsymmetryResult struct {
+	Download Stats `json:"download_ms"`
+	Upload   Stats `json:"upload_ms"`
+
+	// Difference is the median upload latency minus the median download
+	// latency in ms
+	Difference float64 `json:"difference_ms"`
+
+	// Asymmetric is set when the difference exceeds the jitter of both
+	// directions, so it is unlikely to be noise
+	Asymmetric bool `json:"asymmetric"`
+}
+
+// ProbeAsymmetry alternates count small downloads with count small uploads of
+// the same size and compares their latency, excluding server processing
+// time. Without synchronized clocks or raw sockets the one-way delays cannot
+// be measured, so this is an approximation: a download probe carries its
+// payload on the return path and an upload probe on the forward path, so a
+// consistent difference hints at queuing or routing that differs between
+// the two directions.
+func (c *Client) ProbeAsymmetry(ctx context.Context, count int) (*AsymmetryResult, error) {
+	upload := make([]byte, asymmetryPayload)
+	c.payloadRng.Read(upload)
+
+	var downloads, uploads []float64
+	for i := 0; i < count; i++ {
+		if timing, err := c.download(ctx, asymmetryPayload); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else {
+			downloads = append(downloads, timing.ttfb.Sub(timing.started).Seconds()*1000-timing.serverTiming)
+		}
+
+		if timing, err := c.uploadData(ctx, upload); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else {
+			uploads = append(uploads, timing.ttfb.Sub(timing.started).Seconds()*1000-timing.serverTiming)
+		}
+	}
+
+	if len(downloads) == 0 || len(uploads) == 0 {
+		return nil, errors.New("not enough probes succeeded in both directions")
+	}
+
+	result := &AsymmetryResult{
+		Download: math.Summarize(downloads),
+		Upload:   math.Summarize(uploads),
+	}
+	result.Difference = result.Upload.Median - result.Download.Median
+	noise := result.Download.StdDev + result.Upload.StdDev
+	result.Asymmetric = result.Difference > noise || -result.Difference > noise
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package speedtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Backend is a speed test service that measurements are made against. The
+// Client times requests, retries them and applies the transport options; a
+// Backend only knows the service's endpoints and how to read its metadata.
+type Backend interface {
+	// Download returns a GET request for a response body of the given size
+	Download(ctx context.Context, bytes int) (*http.Request, error)
+
+	// Upload returns a request that sends body, whose length the Client sets
+	Upload(ctx context.Context, body io.Reader) (*http.Request, error)
+
+	// Trace describes the serving location and the client's address using
+	// client, which carries the Client's transport options. Retries are
+	// handled by the caller.
+	Trace(ctx context.Context, client *http.Client) (*TraceInfo, error)
+}
+
+// TraceInfo describes a connection to a backend. Any field may be empty if
+// the backend does not report it.
+type TraceInfo struct {
+	Colo     string // code of the serving location, e.g. an IATA code
+	City     string // city of the serving location
+	IP       string // client address as seen by the server
+	Location string // client country
+
+	// Warnings lists signs that the response was intercepted by something
+	// other than the backend
+	Warnings []string
+}
+
+// CloudflareBackend measures against speed.cloudflare.com or another host
+// exposing the same endpoints
+type CloudflareBackend struct {
+	Host string
+
+	// Scheme is the URL scheme of the endpoints, "https" when empty
+	Scheme string
+}
+
+// NewCloudflareBackend returns a backend for host, DefaultHost when empty
+func NewCloudflareBackend(host string) *CloudflareBackend {
+	if host == "" {
+		host = DefaultHost
+	}
+	return &CloudflareBackend{Host: host}
+}
+
+func (b *CloudflareBackend) url(path string) string {
+	scheme := b.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, b.Host, path)
+}
+
+// Download requests /__down, which responds with the requested number of bytes
+func (b *CloudflareBackend) Download(ctx context.Context, bytes int) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, "GET", b.url(fmt.Sprintf("/__down?bytes=%d", bytes)), nil)
+}
+
+// Upload posts to /__up, which reports its processing time in Server-Timing
+func (b *CloudflareBackend) Upload(ctx context.Context, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, "POST", b.url("/__up"), body)
+}
+
+// Trace reads /cdn-cgi/trace and names the serving colo using /locations
+func (b *CloudflareBackend) Trace(ctx context.Context, client *http.Client) (*TraceInfo, error) {
+	locations, err := b.locations(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch server location data: %w", err)
+	}
+
+	resp, err := fetchWith(ctx, client, b.url("/cdn-cgi/trace"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch CDN trace: %w", err)
+	}
+	trace := parseTrace(resp.body)
+
+	return &TraceInfo{
+		Colo:     trace["colo"],
+		City:     locations[trace["colo"]],
+		IP:       trace["ip"],
+		Location: trace["loc"],
+		Warnings: interceptionWarnings(resp, b.Host, b.Scheme, trace),
+	}, nil
+}
+
+// locations returns the map of Cloudflare location IATA codes to city names
+func (b *CloudflareBackend) locations(ctx context.Context, client *http.Client) (map[string]string, error) {
+	resp, err := fetchWith(ctx, client, b.url("/locations"))
+	if err != nil {
+		return nil, err
+	}
+
+	var locations []struct {
+		IATA string `json:"iata"`
+		City string `json:"city"`
+	}
+	if err := json.Unmarshal(resp.body, &locations); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string)
+	for _, loc := range locations {
+		result[loc.IATA] = loc.City
+	}
+	return result, nil
+}
+
+// parseTrace parses the key=value lines of a /cdn-cgi/trace response
+func parseTrace(body []byte) map[string]string {
+	result := make(map[string]string)
+	for _, line := range strings.Split(string(body), "\n") {
+		parts := strings.Split(line, "=")
+		if len(parts) == 2 {
+			result[parts[0]] = parts[1]
+		}
+	}
+	return result
+}
+
+// fetchWith performs a single GET and reads the whole response
+func fetchWith(ctx context.Context, client *http.Client, url string) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &response{body: data, header: resp.Header, tls: resp.TLS}, nil
+}
@@ -0,0 +1,47 @@
+package speedtest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrEndpointUnreachable is returned once Options.MaxConsecutiveFailures
+// requests in a row have failed. The run is aborted rather than working
+// through every remaining measurement against an endpoint that is down.
+var ErrEndpointUnreachable = errors.New("endpoint unreachable")
+
+// circuitBreaker counts consecutive failed requests and, once the limit is
+// reached, fails every further request without sending it
+type circuitBreaker struct {
+	limit int
+
+	mu          sync.Mutex
+	consecutive int
+	last        error
+}
+
+// allow returns an error if the breaker has tripped
+func (b *circuitBreaker) allow() error {
+	if b.limit <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.consecutive >= b.limit {
+		return fmt.Errorf("%w: %d consecutive requests failed, last error: %v", ErrEndpointUnreachable, b.consecutive, b.last)
+	}
+	return nil
+}
+
+// record notes the outcome of a request attempt
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.consecutive = 0
+		return
+	}
+	b.consecutive++
+	b.last = err
+}
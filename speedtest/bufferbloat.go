@@ -0,0 +1,104 @@
+package speedtest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/analysis"
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+// loadedProbeInterval is the pause between latency probes sent while a
+// transfer is running. Probes are small, so they barely reduce the
+// throughput they run alongside.
+const loadedProbeInterval = 200 * time.Millisecond
+
+// LoadedLatency compares latency measured while downloading and uploading
+// with the idle latency. The increase under load is bufferbloat. Fields for
+// a direction that was not probed are zero.
+type LoadedLatency struct {
+	// Download and Upload are the median latencies in ms during each phase
+	Download float64 `json:"download_ms"`
+	Upload   float64 `json:"upload_ms"`
+
+	// DownloadIncrease and UploadIncrease are how much higher each is than
+	// the idle latency
+	DownloadIncrease float64 `json:"download_increase_ms"`
+	UploadIncrease   float64 `json:"upload_increase_ms"`
+
+	// DownloadGrade and UploadGrade rate each direction from A+ to F. Upload
+	// buffers are often larger than download buffers, so the two can differ
+	// widely on asymmetric links.
+	DownloadGrade string `json:"download_grade,omitempty"`
+	UploadGrade   string `json:"upload_grade,omitempty"`
+
+	// Grade rates the larger of the two increases. It is "N/A" when every
+	// probe failed, leaving nothing to grade.
+	Grade string `json:"grade"`
+}
+
+// newLoadedLatency summarizes the probes sent during each transfer phase
+// against the idle latency
+func newLoadedLatency(idle float64, download, upload []float64) *LoadedLatency {
+	l := &LoadedLatency{
+		Download: math.Median(download),
+		Upload:   math.Median(upload),
+	}
+	if len(download) > 0 {
+		l.DownloadIncrease = l.Download - idle
+		l.DownloadGrade = analysis.BufferbloatGrade(l.DownloadIncrease)
+	}
+	if len(upload) > 0 {
+		l.UploadIncrease = l.Upload - idle
+		l.UploadGrade = analysis.BufferbloatGrade(l.UploadIncrease)
+	}
+	if len(download) == 0 && len(upload) == 0 {
+		// An increase of 0 would earn the top grade
+		l.Grade = "N/A"
+		return l
+	}
+	increase := l.DownloadIncrease
+	if l.UploadIncrease > increase {
+		increase = l.UploadIncrease
+	}
+	l.Grade = analysis.BufferbloatGrade(increase)
+	return l
+}
+
+// probeUnderLoad sends latency probes one after another in the background
+// until the returned function is called, which returns their latencies in
+// ms. The function may be called more than once. Probes are not retried;
+// failed ones are left out, as a link saturated enough to drop them shows up
+// in the ones that succeed.
+func (c *Client) probeUnderLoad(ctx context.Context) func() []float64 {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan []float64, 1)
+	go func() {
+		var samples []float64
+		for ctx.Err() == nil {
+			timing, err := c.requestOnce(ctx, c.probeRequest, nil)
+			if err == nil {
+				samples = append(samples, c.probeLatency(timing))
+			}
+
+			timer := time.NewTimer(loadedProbeInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+		done <- samples
+	}()
+
+	var once sync.Once
+	var samples []float64
+	return func() []float64 {
+		once.Do(func() {
+			cancel()
+			samples = <-done
+		})
+		return samples
+	}
+}
@@ -0,0 +1,20 @@
+package speedtest
+
+import "testing"
+
+func TestNewLoadedLatencyWithoutProbes(t *testing.T) {
+	l := newLoadedLatency(20, nil, nil)
+	if l.Grade != "N/A" || l.DownloadGrade != "" || l.UploadGrade != "" {
+		t.Errorf("grades = %q, %q, %q, want N/A and none per direction", l.Grade, l.DownloadGrade, l.UploadGrade)
+	}
+	warnings := NewClient(Options{}).warnings(&Result{LoadedLatency: l})
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %q, want one about the failed probes", warnings)
+	}
+
+	// Only the direction that was probed is graded
+	l = newLoadedLatency(20, []float64{25, 30, 35}, nil)
+	if l.Grade == "N/A" || l.DownloadGrade != l.Grade || l.UploadGrade != "" {
+		t.Errorf("grades = %q, %q, %q, want the download grade overall", l.Grade, l.DownloadGrade, l.UploadGrade)
+	}
+}
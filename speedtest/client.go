@@ -0,0 +1,777 @@
+// Package speedtest measures latency, download and upload speeds against
+// Cloudflare's speed test service.
+package speedtest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/iocount"
+	"github.com/coleaeason/cloudflare-speed/internal/throttle"
+)
+
+// DefaultHost is the speed test service measured against when Options.Host is empty
+const DefaultHost = "speed.cloudflare.com"
+
+// retryBackoff is the nominal delay before the first retry, doubled on each
+// further attempt. The actual delay is randomized to between half and one and
+// a half times the nominal value so that concurrent clients do not retry in step.
+const retryBackoff = 500 * time.Millisecond
+
+// Options configures a Client. The zero value runs the standard size tiers
+// without retries and reports the 90th percentile.
+type Options struct {
+	// Host serves the speed test endpoints, DefaultHost when empty
+	Host string
+
+	// Scheme is the URL scheme of the endpoints, "https" when empty. "http"
+	// is for local mock servers and self-hosted endpoints without TLS.
+	Scheme string
+
+	// Backend is the speed test service measured against. When nil a
+	// CloudflareBackend for Host is used.
+	Backend Backend
+
+	// Duration, when set, measures download speed by streaming for this long
+	// instead of downloading the fixed size tiers
+	Duration time.Duration
+
+	// SingleStream measures download speed by sampling one sustained response
+	SingleStream bool
+
+	// DownloadTiers and UploadTiers are the sizes measured, nil uses
+	// DefaultDownloadTiers and DefaultUploadTiers. An empty, non-nil list
+	// skips that direction.
+	DownloadTiers []SizeTier
+	UploadTiers   []SizeTier
+
+	// UntilStable, when set, samples each tier until the 95% confidence
+	// interval of its mean speed is within this fraction of the mean, e.g.
+	// 0.05 for ±5%, instead of a fixed number of times. A tier takes at
+	// least 3 samples and at most three times its Iterations; tiers of
+	// fewer than 3 iterations keep their fixed count. It is shorthand for a
+	// StableSampler and ignored when Sampler is set.
+	UntilStable float64
+
+	// Sampler schedules the measurements of each tier, FixedSampler by
+	// default. Failed measurements are still retried once in a second pass
+	// of exactly the number that failed.
+	Sampler Sampler
+
+	// SkipSmallTiers probes the link before downloading and, if it is fast,
+	// leaves download tiers too small to measure it out of the aggregate.
+	// They are still measured and reported with TierResult.Excluded set.
+	SkipSmallTiers bool
+
+	// MinSampleDuration, when set, discards downloads and uploads that
+	// transferred in less time, whose speed reflects latency more than
+	// throughput. They are counted in TierResult.Discarded.
+	MinSampleDuration time.Duration
+
+	// FinishDuration, when set, skips the remaining tiers of a direction
+	// once a request in it took at least this long. The link has then been
+	// measured and larger sizes would only take longer.
+	FinishDuration time.Duration
+
+	// Percentile of all samples reported as the overall download and upload
+	// speed, computed with PercentileMethod (NearestRank by default)
+	Percentile       float64
+	PercentileMethod PercentileMethod
+
+	// Percentiles are additional percentiles of all samples reported in
+	// Result.DownloadPercentiles and Result.UploadPercentiles
+	Percentiles []float64
+
+	// NoTrace skips the trace and locations lookups, leaving the server
+	// location and client IP out of the result
+	NoTrace bool
+
+	// FailFast aborts the run on the first failed measurement. By default
+	// failed measurements are reported and left out, so a run on a flaky
+	// connection still produces partial results.
+	FailFast bool
+
+	// MaxRetries is the number of times a failed request is retried, and
+	// RetryStatuses the HTTP status codes that are considered retryable
+	MaxRetries    int
+	RetryStatuses map[int]bool
+
+	// AbortOnRateLimit fails the run with ErrRateLimited on the first 429
+	// response. By default rate limited requests are retried within
+	// MaxRetries after the delay the server asks for, and reported in
+	// Result.Warnings.
+	AbortOnRateLimit bool
+
+	// MaxConsecutiveFailures aborts the run with ErrEndpointUnreachable once
+	// this many request attempts in a row have failed. Zero never aborts.
+	MaxConsecutiveFailures int
+
+	// TLSMin and TLSMax bound the negotiated TLS version, zero uses the defaults
+	TLSMin uint16
+	TLSMax uint16
+
+	// ClientCertificates are presented to servers that request a client
+	// certificate, e.g. endpoints protected by mutual TLS
+	ClientCertificates []tls.Certificate
+
+	// LatencyConcurrency is how many latency probes may run at once
+	LatencyConcurrency int
+
+	// ProbeSize is the download size in bytes of each latency probe, 1000
+	// when zero. Smaller probes isolate round trip time better.
+	ProbeSize int
+
+	// UploadTiming selects how upload transfer time is measured, ServerTiming
+	// by default
+	UploadTiming UploadTiming
+
+	// IgnoreServerTiming disregards the Server-Timing header entirely, so
+	// that latency is the plain time to first byte and uploads use
+	// ClientTiming, as tools that do not read the header measure
+	IgnoreServerTiming bool
+
+	// ModeBucket, when set, reports the most common latency rounded to
+	// this many ms as Result.LatencyMode
+	ModeBucket float64
+
+	// JitterMethod selects how Result.Jitter is computed, Spread by default
+	JitterMethod JitterMethod
+
+	// LatencyMethod selects what latency probes measure, TTFBLatency by default
+	LatencyMethod LatencyMethod
+
+	// LatencyBudget bounds the latency phase. Once it elapses no further
+	// probes are started and the samples gathered so far are used. Zero
+	// always runs every probe.
+	LatencyBudget time.Duration
+
+	// Seed seeds the random upload payloads, zero picks a different seed each run
+	Seed int64
+
+	// StrictRedirects fails requests that are redirected to a different host
+	StrictRedirects bool
+
+	// RateLimit, when set, caps the combined throughput of all downloads and
+	// uploads to this many bits per second, simulating a slower link
+	RateLimit float64
+
+	// Headers are added to every request, e.g. Cloudflare Access credentials
+	Headers http.Header
+
+	// UserAgent is sent with every request, Go's default when empty
+	UserAgent string
+
+	// UnixSocket, when set, is the path of a Unix domain socket every
+	// connection is made to in place of the host's address, e.g. a local
+	// tunnel or sidecar. Requests are otherwise unchanged, including the
+	// Host header and TLS server name.
+	UnixSocket string
+
+	// Resolver looks up the addresses of the speed test host and is used by
+	// every dial, net.DefaultResolver when nil
+	Resolver *net.Resolver
+
+	// Proxy, when set, routes every request through this proxy. Credentials
+	// in its user info are sent as Proxy-Authorization.
+	Proxy *url.URL
+
+	// LoadedLatency probes latency while downloading and uploading and
+	// reports the increase over idle latency as Result.LoadedLatency
+	LoadedLatency bool
+
+	// UploadLoadedLatency probes latency only while uploading, isolating
+	// upload bufferbloat without slowing the downloads
+	UploadLoadedLatency bool
+
+	// StallTimeout, when set, aborts and retries a request whose transfer
+	// makes no progress for this long
+	StallTimeout time.Duration
+
+	// Clock times requests and measurements, SystemClock when nil
+	Clock Clock
+}
+
+// Client performs requests against the speed test endpoints
+type Client struct {
+	opts Options
+	rng  *lockedRand
+
+	// payloadRng generates upload bodies so they cannot be compressed in transit
+	payloadRng *lockedRand
+
+	// redirects records every redirect followed
+	redirects *redirectLog
+
+	// tally counts requests that failed to get a response
+	tally *connectionTally
+
+	// traffic counts the requests and bytes of each phase
+	traffic *trafficTally
+
+	// breaker stops a run once requests keep failing
+	breaker *circuitBreaker
+
+	// caveats counts issues reported in Result.Warnings
+	caveats *caveatTally
+
+	// bucket limits throughput when a rate limit is configured
+	bucket *throttle.Bucket
+
+	// dialAddr, when set, is the IP address connected to in place of the
+	// resolved hostname. The hostname is still used for TLS SNI and Host.
+	dialAddr string
+
+	// resolver looks up the addresses of the speed test host
+	resolver *net.Resolver
+
+	// transport is shared by every request so that connections are reused
+	// instead of each request paying for its own handshakes. Run closes its
+	// idle connections when it returns.
+	transport *http.Transport
+}
+
+// NewClient returns a Client configured with opts
+func NewClient(opts Options) *Client {
+	if opts.Host == "" {
+		opts.Host = DefaultHost
+	}
+	if opts.Scheme == "" {
+		opts.Scheme = "https"
+	}
+	if opts.Backend == nil {
+		opts.Backend = &CloudflareBackend{Host: opts.Host, Scheme: opts.Scheme}
+	}
+	if opts.Percentile == 0 {
+		opts.Percentile = 90
+	}
+	if opts.PercentileMethod == "" {
+		opts.PercentileMethod = NearestRank
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = net.DefaultResolver
+	}
+	if opts.Clock == nil {
+		opts.Clock = SystemClock{}
+	}
+	if opts.Sampler == nil {
+		opts.Sampler = FixedSampler{}
+		if opts.UntilStable > 0 {
+			opts.Sampler = StableSampler{Threshold: opts.UntilStable}
+		}
+	}
+	if opts.DownloadTiers == nil {
+		opts.DownloadTiers = DefaultDownloadTiers
+	}
+	if opts.UploadTiers == nil {
+		opts.UploadTiers = DefaultUploadTiers
+	}
+	if opts.ProbeSize == 0 {
+		opts.ProbeSize = 1000
+	}
+	if opts.IgnoreServerTiming {
+		opts.UploadTiming = ClientTiming
+	}
+	if opts.UploadTiming == "" {
+		opts.UploadTiming = ServerTiming
+	}
+	if opts.JitterMethod == "" {
+		opts.JitterMethod = Spread
+	}
+	if opts.LatencyMethod == "" {
+		opts.LatencyMethod = TTFBLatency
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	c := &Client{
+		opts:       opts,
+		rng:        &lockedRand{rng: rand.New(rand.NewSource(time.Now().UnixNano()))},
+		payloadRng: &lockedRand{rng: rand.New(rand.NewSource(seed))},
+		redirects:  &redirectLog{},
+		tally:      &connectionTally{},
+		traffic:    newTrafficTally(),
+		breaker:    &circuitBreaker{limit: opts.MaxConsecutiveFailures},
+		caveats:    &caveatTally{},
+		resolver:   opts.Resolver,
+	}
+	c.transport = c.newTransport()
+	if opts.RateLimit > 0 {
+		c.bucket = throttle.NewBucket(opts.RateLimit / 8)
+	}
+	return c
+}
+
+// limit wraps r so that it is read no faster than the configured rate limit.
+// A read waiting for the limit fails once ctx is done.
+func (c *Client) limit(ctx context.Context, r io.Reader) io.Reader {
+	if c.bucket == nil {
+		return r
+	}
+	return throttle.NewReader(ctx, r, c.bucket)
+}
+
+// lockedRand is a random source that is safe for concurrent use
+type lockedRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (r *lockedRand) Int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Int63n(n)
+}
+
+func (r *lockedRand) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Read(p)
+}
+
+// statusError is returned when the server responds with a non-2xx status
+type statusError struct {
+	code   int
+	status string
+
+	// retryAfter is the Retry-After header, a delay or the date to retry
+	// at, resolved against the client's clock when the retry is scheduled
+	retryAfter string
+}
+
+func (e *statusError) Error() string {
+	return "unexpected response status " + e.status
+}
+
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{code: resp.StatusCode, status: resp.Status,
+			retryAfter: resp.Header.Get("Retry-After")}
+	}
+	return nil
+}
+
+// aborts reports whether a failed measurement ends the run rather than being
+// reported and left out
+func (c *Client) aborts(err error) bool {
+	return c.opts.FailFast || errors.Is(err, ErrEndpointUnreachable) || errors.Is(err, ErrRateLimited)
+}
+
+// withRetries calls fn until it succeeds, fails with a status that is not
+// configured for retries, the retry budget is exhausted, or ctx is done.
+// Transport errors and rate limited requests are always retried.
+func (c *Client) withRetries(ctx context.Context, fn func() error) error {
+	delay := retryBackoff
+	for attempt := 0; ; attempt++ {
+		if err := c.breaker.allow(); err != nil {
+			return err
+		}
+		err := fn()
+		if ctx.Err() == nil {
+			c.breaker.record(err)
+		}
+		var se *statusError
+		limited := errors.As(err, &se) && se.code == http.StatusTooManyRequests
+		if limited {
+			c.caveats.rateLimited()
+			if c.opts.AbortOnRateLimit {
+				return fmt.Errorf("%w: %v", ErrRateLimited, err)
+			}
+		}
+		if err == nil || attempt >= c.opts.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		// Rate limited requests are always retried, after the delay the
+		// server asks for when it is longer than the usual backoff
+		wait := delay/2 + time.Duration(c.rng.Int63n(int64(delay)))
+		if limited {
+			if asked := retryAfter(se.retryAfter, c.opts.Clock.Now()); asked > wait {
+				wait = asked
+			}
+			if wait > maxRateLimitWait {
+				wait = maxRateLimitWait
+			}
+		} else if se != nil && !c.opts.RetryStatuses[se.code] {
+			return err
+		}
+		if errors.Is(err, errCrossHostRedirect) {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// newTransport returns a transport configured with the client's TLS and dialing options
+func (c *Client) newTransport() *http.Transport {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: false,
+			MinVersion:         c.opts.TLSMin,
+			MaxVersion:         c.opts.TLSMax,
+			Certificates:       c.opts.ClientCertificates,
+		},
+	}
+	transport.DialContext = c.dial
+	if c.opts.Proxy != nil {
+		transport.Proxy = http.ProxyURL(c.opts.Proxy)
+	}
+	return transport
+}
+
+// newHTTPClient returns an HTTP client using the client's transport and
+// redirect policy. A zero timeout means no timeout.
+func (c *Client) newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &countingTransport{
+			base:    &headerTransport{base: connectionTransport{c}, userAgent: c.opts.UserAgent, headers: c.opts.Headers},
+			tally:   c.tally,
+			traffic: c.traffic,
+		},
+		Timeout:       timeout,
+		CheckRedirect: c.checkRedirect,
+	}
+}
+
+// connectionTransport sends requests over the client's shared connections,
+// except requests with Close set, which each get a new connection so that
+// its handshakes are part of the request
+type connectionTransport struct {
+	c *Client
+}
+
+func (t connectionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Close {
+		transport := t.c.newTransport()
+		transport.DisableKeepAlives = true
+		return transport.RoundTrip(req)
+	}
+	return t.c.transport.RoundTrip(req)
+}
+
+// errCrossHostRedirect is returned when strict redirects are enabled and a
+// response redirects to a different host
+var errCrossHostRedirect = errors.New("refusing cross-host redirect")
+
+// redirectLog collects the redirects followed during a run
+type redirectLog struct {
+	mu        sync.Mutex
+	redirects []string
+}
+
+func (l *redirectLog) add(redirect string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.redirects = append(l.redirects, redirect)
+}
+
+func (l *redirectLog) list() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.redirects...)
+}
+
+// checkRedirect records each redirect so it can be reported, since following
+// one silently could skew timings or measure an unexpected host
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	c.redirects.add(fmt.Sprintf("%s -> %s", via[len(via)-1].URL, req.URL))
+	if c.opts.StrictRedirects && req.URL.Host != via[0].URL.Host {
+		return fmt.Errorf("%w from %s to %s", errCrossHostRedirect, via[0].URL.Host, req.URL.Host)
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// response is a fully read response from fetchWith
+type response struct {
+	body   []byte
+	header http.Header
+	tls    *tls.ConnectionState
+}
+
+// headerTransport adds the configured User-Agent and extra headers to every
+// request, including those a Backend builds itself
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	headers   http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.userAgent == "" && len(t.headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	for key, values := range t.headers {
+		// Host is taken from the request rather than the header map
+		if http.CanonicalHeaderKey(key) == "Host" {
+			req.Host = values[len(values)-1]
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Locations returns the map of Cloudflare location IATA codes to city names
+// from the configured host
+func (c *Client) Locations(ctx context.Context) (map[string]string, error) {
+	backend := &CloudflareBackend{Host: c.opts.Host, Scheme: c.opts.Scheme}
+	httpClient := c.newHTTPClient(metadataTimeout)
+
+	var locations map[string]string
+	err := c.withRetries(ctx, func() error {
+		var err error
+		locations, err = backend.locations(ctx, httpClient)
+		return err
+	})
+	return locations, err
+}
+
+// Trace describes the serving location and the client's address as reported
+// by the backend, including any signs that responses are being intercepted
+func (c *Client) Trace(ctx context.Context) (*TraceInfo, error) {
+	httpClient := c.newHTTPClient(metadataTimeout)
+
+	var info *TraceInfo
+	err := c.withRetries(ctx, func() error {
+		var err error
+		info, err = c.opts.Backend.Trace(ctx, httpClient)
+		return err
+	})
+	return info, err
+}
+
+type requestTiming struct {
+	started      time.Time
+	dnsLookup    time.Time
+	tcpStart     time.Time
+	tcpHandshake time.Time
+	sslHandshake time.Time
+	ttfb         time.Time
+	ended        time.Time
+	serverTiming float64
+	tlsVersion   uint16
+
+	// received is the number of response body bytes actually read, which
+	// may differ from the size requested
+	received int64
+
+	// bodyStarted is when the transport started reading the request body,
+	// zero for requests without one
+	bodyStarted time.Time
+}
+
+// timedReader records when reading starts. The transport may read a request
+// body from another goroutine, so access is locked.
+type timedReader struct {
+	r     io.Reader
+	clock Clock
+
+	mu      sync.Mutex
+	started time.Time
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	t.mu.Lock()
+	if t.started.IsZero() {
+		t.started = t.clock.Now()
+	}
+	t.mu.Unlock()
+	return t.r.Read(p)
+}
+
+func (t *timedReader) startedAt() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.started
+}
+
+// requestBuilder creates a request sending body, typically a Backend method
+type requestBuilder func(ctx context.Context, body io.Reader) (*http.Request, error)
+
+func (c *Client) request(ctx context.Context, build requestBuilder, data []byte) (*requestTiming, error) {
+	var timing *requestTiming
+	err := c.withRetries(ctx, func() error {
+		var err error
+		timing, err = c.requestOnce(ctx, build, data)
+		return err
+	})
+	return timing, err
+}
+
+func (c *Client) requestOnce(ctx context.Context, build requestBuilder, data []byte) (*requestTiming, error) {
+	if c.opts.StallTimeout <= 0 {
+		return c.transfer(ctx, build, data, nil)
+	}
+	ctx, watchdog := watchStalls(ctx, c.opts.StallTimeout, c.opts.Clock)
+	defer watchdog.stop()
+	timing, err := c.transfer(ctx, build, data, watchdog)
+	return timing, watchdog.err(err)
+}
+
+// transfer sends a single request and reads its response. Reads of both
+// bodies are reported to watchdog when one is given.
+func (c *Client) transfer(ctx context.Context, build requestBuilder, data []byte, watchdog *stallWatchdog) (*requestTiming, error) {
+	timing := &requestTiming{
+		started: c.opts.Clock.Now(),
+	}
+
+	httpClient := c.newHTTPClient(0)
+
+	body := &timedReader{r: watchdog.watch(c.limit(ctx, strings.NewReader(string(data)))), clock: c.opts.Clock}
+	req, err := build(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSDone: func(dnsInfo httptrace.DNSDoneInfo) {
+			timing.dnsLookup = c.opts.Clock.Now()
+		},
+		ConnectStart: func(network, addr string) {
+			if timing.tcpStart.IsZero() {
+				timing.tcpStart = c.opts.Clock.Now()
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			timing.tcpHandshake = c.opts.Clock.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			timing.sslHandshake = c.opts.Clock.Now()
+		},
+		GotFirstResponseByte: func() {
+			timing.ttfb = c.opts.Clock.Now()
+		},
+	}
+
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	if resp.TLS != nil {
+		timing.tlsVersion = resp.TLS.Version
+	}
+	if resp.Uncompressed || resp.Header.Get("Content-Encoding") != "" {
+		c.caveats.compressedResponse()
+	}
+	if rateLimitExhausted(resp.Header) {
+		c.caveats.rateLimitExhausted()
+	}
+
+	// Read the entire response to ensure timing.ended is accurate. A
+	// download cut off part way is resumed rather than thrown away.
+	counted := iocount.NewCountingReader(watchdog.watch(resp.Body), nil)
+	_, err = io.Copy(io.Discard, c.limit(ctx, counted))
+	received := counted.Count()
+	if err != nil && req.Method == "GET" && received > 0 && ctx.Err() == nil {
+		received, err = c.resumeDownload(ctx, httpClient, req.URL.String(), received, err, watchdog)
+	}
+	if err != nil {
+		return nil, err
+	}
+	timing.received = received
+
+	timing.ended = c.opts.Clock.Now()
+	if len(data) > 0 {
+		timing.bodyStarted = body.startedAt()
+	}
+
+	// Parse server timing header if available. A header sent several times
+	// is one list, as if the values were joined with commas.
+	if serverTiming := resp.Header.Values("Server-Timing"); len(serverTiming) > 0 && !c.opts.IgnoreServerTiming {
+		timing.serverTiming = parseServerTiming(strings.Join(serverTiming, ","), timing.ended.Sub(timing.started))
+	}
+
+	return timing, nil
+}
+
+// tlsVersions maps TLS version names to their versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion returns the version for a name such as "1.2"
+func ParseTLSVersion(name string) (uint16, error) {
+	version, ok := tlsVersions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q", name)
+	}
+	return version, nil
+}
+
+// TLSVersionName returns a human readable name such as "TLS 1.3" for a
+// version, or "none" for 0 when the connection was not encrypted
+func TLSVersionName(version uint16) string {
+	if version == 0 {
+		return "none"
+	}
+	for name, v := range tlsVersions {
+		if v == version {
+			return "TLS " + name
+		}
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+func (c *Client) download(ctx context.Context, bytes int) (*requestTiming, error) {
+	return c.request(ctx, func(ctx context.Context, _ io.Reader) (*http.Request, error) {
+		return c.opts.Backend.Download(ctx, bytes)
+	}, nil)
+}
+
+func (c *Client) upload(ctx context.Context, bytes int) (*requestTiming, error) {
+	data := make([]byte, bytes)
+	c.payloadRng.Read(data)
+	return c.uploadData(ctx, data)
+}
+
+// uploadData uploads data through the backend
+func (c *Client) uploadData(ctx context.Context, data []byte) (*requestTiming, error) {
+	return c.request(ctx, c.opts.Backend.Upload, data)
+}
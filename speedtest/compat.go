@@ -0,0 +1,50 @@
+package speedtest
+
+import "time"
+
+// CompatDownloadTiers and CompatUploadTiers are the sizes and counts measured
+// by the speed.cloudflare.com web client. Its first 100kB download, an initial
+// estimate, is counted here as one more iteration.
+var (
+	CompatDownloadTiers = []SizeTier{
+		{"100kB", 100000, 10},
+		{"1MB", 1000000, 8},
+		{"10MB", 10000000, 6},
+		{"25MB", 25000000, 4},
+		{"100MB", 100000000, 3},
+		{"250MB", 250000000, 2},
+	}
+	CompatUploadTiers = []SizeTier{
+		{"100kB", 100000, 8},
+		{"1MB", 1000000, 6},
+		{"10MB", 10000000, 4},
+		{"25MB", 25000000, 4},
+		{"50MB", 50000000, 3},
+	}
+)
+
+// CloudflareCompat returns opts changed to measure the way the
+// speed.cloudflare.com web client does, so that results can be compared with
+// the website: its sizes and counts, the 90th percentile interpolated between
+// samples, jitter between consecutive probes, samples shorter than 10ms
+// discarded and larger sizes skipped once a request takes a second. The web
+// client interleaves downloads and uploads while this runs each direction in
+// turn, which should not change the results.
+func CloudflareCompat(opts Options) Options {
+	opts.DownloadTiers = CompatDownloadTiers
+	opts.UploadTiers = CompatUploadTiers
+	opts.Percentile = 90
+	opts.PercentileMethod = Interpolated
+	opts.JitterMethod = Consecutive
+	opts.LatencyMethod = TTFBLatency
+	opts.UploadTiming = ServerTiming
+	opts.MinSampleDuration = 10 * time.Millisecond
+	opts.FinishDuration = time.Second
+	return opts
+}
+
+// finished reports whether a tier whose longest request took longest ends
+// its direction, see Options.FinishDuration
+func (c *Client) finished(longest time.Duration) bool {
+	return c.opts.FinishDuration > 0 && longest >= c.opts.FinishDuration
+}
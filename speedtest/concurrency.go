@@ -0,0 +1,78 @@
+package speedtest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// concurrencyGain is the relative increase in aggregate throughput below
+// which doubling the streams no longer counts as helping
+const concurrencyGain = 0.1
+
+// ConcurrencyLevel is the aggregate download throughput with a number of
+// parallel streams
+type ConcurrencyLevel struct {
+	Streams int     `json:"streams"`
+	Mbps    float64 `json:"mbps"`
+}
+
+// ConcurrencyResult describes how download throughput scaled with parallel
+// streams
+type ConcurrencyResult struct {
+	Levels []ConcurrencyLevel `json:"levels"`
+
+	// Knee is the number of streams after which doubling them raised the
+	// throughput by less than 10%, the fewest that saturate the link. It is
+	// the last level measured if throughput was still rising.
+	Knee int `json:"knee_streams"`
+
+	// Capacity is the throughput at Knee
+	Capacity float64 `json:"capacity_mbps"`
+}
+
+// MeasureConcurrency downloads with 1, 2, 4 and so on parallel streams, each
+// level for duration, up to maxStreams. It stops early once doubling the
+// streams no longer increases the aggregate throughput. Every stream has a
+// connection of its own.
+func (c *Client) MeasureConcurrency(ctx context.Context, maxStreams int, duration time.Duration) (*ConcurrencyResult, error) {
+	result := &ConcurrencyResult{}
+	for streams := 1; streams <= maxStreams; streams *= 2 {
+		mbps, err := c.measureParallel(ctx, streams, duration)
+		if err != nil {
+			return nil, err
+		}
+		result.Levels = append(result.Levels, ConcurrencyLevel{Streams: streams, Mbps: mbps})
+
+		if result.Knee > 0 && mbps < result.Capacity*(1+concurrencyGain) {
+			return result, nil
+		}
+		result.Knee, result.Capacity = streams, mbps
+	}
+	return result, nil
+}
+
+// measureParallel runs streams fixed-duration downloads at once and returns
+// their combined throughput
+func (c *Client) measureParallel(ctx context.Context, streams int, duration time.Duration) (float64, error) {
+	speeds := make([]float64, streams)
+	errs := make([]error, streams)
+	var wg sync.WaitGroup
+	for i := 0; i < streams; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			speeds[i], errs[i] = c.measureDownloadDuration(ctx, duration)
+		}(i)
+	}
+	wg.Wait()
+
+	var total float64
+	for i := range speeds {
+		if errs[i] != nil {
+			return 0, errs[i]
+		}
+		total += speeds[i]
+	}
+	return total, nil
+}
@@ -0,0 +1,116 @@
+package speedtest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+)
+
+// hostAddr returns the address requests connect to and their scheme, from
+// the backend's host when it is a CloudflareBackend and Options.Host
+// otherwise. The port defaults to the scheme's, 443 for https and 80 for http.
+func (c *Client) hostAddr() (addr, scheme string) {
+	host, scheme := c.opts.Host, c.opts.Scheme
+	if b, ok := c.opts.Backend.(*CloudflareBackend); ok {
+		host, scheme = b.Host, b.Scheme
+	}
+	if scheme == "" {
+		scheme = "https"
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host, scheme
+	}
+	port := "443"
+	if scheme == "http" {
+		port = "80"
+	}
+	return net.JoinHostPort(host, port), scheme
+}
+
+// proxyAddr returns the address of the proxy requests go through, empty
+// without one
+func (c *Client) proxyAddr() string {
+	proxy := c.opts.Proxy
+	if proxy == nil {
+		return ""
+	}
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+	port := map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxy.Scheme]
+	return net.JoinHostPort(proxy.Hostname(), port)
+}
+
+// LookupHost resolves the speed test host with the client's resolver. It
+// returns nothing without a lookup when requests go to a Unix socket, or
+// through a proxy, which resolves the host itself.
+func (c *Client) LookupHost(ctx context.Context) ([]string, error) {
+	if c.opts.UnixSocket != "" || c.opts.Proxy != nil {
+		return nil, nil
+	}
+	addr, _ := c.hostAddr()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.resolver.LookupHost(ctx, host)
+}
+
+// CheckConnection opens a TCP connection the way requests do, to the proxy
+// or Unix socket when one is set and to the speed test host otherwise, and
+// returns the address connected to
+func (c *Client) CheckConnection(ctx context.Context) (string, error) {
+	addr := c.proxyAddr()
+	if addr == "" {
+		addr, _ = c.hostAddr()
+	}
+	conn, err := c.dial(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.RemoteAddr().String(), nil
+}
+
+// CheckTLS completes a TLS handshake with the speed test host the way
+// requests do, through the proxy when one is set, and returns the
+// negotiated connection state. The request that carries the handshake is
+// sent on a connection of its own, and its response is not checked.
+func (c *Client) CheckTLS(ctx context.Context) (*tls.ConnectionState, error) {
+	addr, scheme := c.hostAddr()
+	if scheme != "https" {
+		return nil, errors.New("the " + scheme + " scheme does not use TLS")
+	}
+
+	var state *tls.ConnectionState
+	var handshakeErr error
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeDone: func(s tls.ConnectionState, err error) {
+			state, handshakeErr = &s, err
+		},
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), "HEAD", "https://"+addr+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := c.newTransport()
+	transport.DisableKeepAlives = true
+	resp, err := transport.RoundTrip(req)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if handshakeErr != nil {
+		return nil, handshakeErr
+	}
+	if state == nil {
+		if err == nil {
+			err = errors.New("no TLS handshake took place")
+		}
+		return nil, err
+	}
+	return state, nil
+}
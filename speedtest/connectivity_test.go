@@ -0,0 +1,60 @@
+package speedtest
+
+import (
+	"context"
+	"net/url"
+	"testing"
+)
+
+func TestHostAddr(t *testing.T) {
+	tests := []struct {
+		opts   Options
+		addr   string
+		scheme string
+	}{
+		{opts: Options{}, addr: "speed.cloudflare.com:443", scheme: "https"},
+		{opts: Options{Host: "localhost", Scheme: "http"}, addr: "localhost:80", scheme: "http"},
+		{opts: Options{Host: "localhost:8080", Scheme: "http"}, addr: "localhost:8080", scheme: "http"},
+		{opts: Options{Backend: &CloudflareBackend{Host: "backend.example.com", Scheme: "http"}}, addr: "backend.example.com:80", scheme: "http"},
+	}
+	for _, tt := range tests {
+		addr, scheme := NewClient(tt.opts).hostAddr()
+		if addr != tt.addr || scheme != tt.scheme {
+			t.Errorf("%+v: got %q, %q, want %q, %q", tt.opts, addr, scheme, tt.addr, tt.scheme)
+		}
+	}
+}
+
+func TestProxyAddr(t *testing.T) {
+	tests := map[string]string{
+		"http://proxy.example.com":              "proxy.example.com:80",
+		"https://proxy.example.com":             "proxy.example.com:443",
+		"socks5://proxy.example.com":            "proxy.example.com:1080",
+		"http://user:pw@proxy.example.com:3128": "proxy.example.com:3128",
+	}
+	for raw, want := range tests {
+		proxy, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := NewClient(Options{Proxy: proxy}).proxyAddr(); got != want {
+			t.Errorf("%s: got %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestCheckConnectionUsesHostPort(t *testing.T) {
+	srv := newMockServer(t)
+	client := NewClient(mockOptions(srv))
+
+	addr, err := client.CheckConnection(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != srv.Listener.Addr().String() {
+		t.Errorf("connected to %s, want %s", addr, srv.Listener.Addr())
+	}
+	if _, err := client.CheckTLS(context.Background()); err == nil {
+		t.Error("CheckTLS succeeded over http")
+	}
+}
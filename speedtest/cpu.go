@@ -0,0 +1,32 @@
+package speedtest
+
+import "time"
+
+// cpuBoundPercent is the share of one core above which the download is
+// suspected to be limited by the CPU. Downloads run one at a time, so
+// decrypting TLS can use at most about one core however many the device has.
+const cpuBoundPercent = 80
+
+// cpuMeter measures the CPU time the process uses over a span of wall time
+type cpuMeter struct {
+	clock   Clock
+	started time.Time
+	cpu     time.Duration
+	ok      bool
+}
+
+func startCPUMeter(clock Clock) cpuMeter {
+	cpu, ok := processCPUTime()
+	return cpuMeter{clock: clock, started: clock.Now(), cpu: cpu, ok: ok}
+}
+
+// percent returns the CPU time used since the meter started as a percentage
+// of one core, or false where the platform does not report CPU time
+func (m cpuMeter) percent() (float64, bool) {
+	cpu, ok := processCPUTime()
+	wall := m.clock.Now().Sub(m.started)
+	if !m.ok || !ok || wall <= 0 {
+		return 0, false
+	}
+	return float64(cpu-m.cpu) / float64(wall) * 100, true
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package speedtest
+
+import "time"
+
+// processCPUTime is unavailable since this platform has no getrusage
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
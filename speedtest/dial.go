@@ -0,0 +1,65 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// dial connects to addr, to dialAddr when probing a single address, or to
+// the Unix socket when one is configured. When
+// the connection fails the error says which address families the host
+// resolved to, so a host that is only reachable over IPv4 on an IPv6-only
+// network, or the reverse, is easy to recognize.
+func (c *Client) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.opts.UnixSocket != "" {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", c.opts.UnixSocket)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if c.dialAddr != "" {
+		host = c.dialAddr
+	}
+
+	// The dialer tries every A and AAAA record, racing the two families
+	// when both exist, so AAAA-only hosts need no special handling
+	dialer := &net.Dialer{Resolver: c.resolver}
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(host, port))
+	if err != nil && ctx.Err() == nil {
+		return nil, c.explainDialError(ctx, host, err)
+	}
+	return conn, err
+}
+
+// explainDialError adds the address families host resolves to to a failed dial
+func (c *Client) explainDialError(ctx context.Context, host string, err error) error {
+	if net.ParseIP(host) != nil {
+		return err
+	}
+	addrs, lookupErr := c.resolver.LookupIPAddr(ctx, host)
+	if lookupErr != nil {
+		return err
+	}
+
+	var v4, v6 int
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			v4++
+		} else {
+			v6++
+		}
+	}
+	switch {
+	case v4 == 0 && v6 == 0:
+		return fmt.Errorf("%s has no A or AAAA records: %w", host, err)
+	case v4 == 0:
+		return fmt.Errorf("%s only has IPv6 (AAAA) addresses and none are reachable, check IPv6 connectivity: %w", host, err)
+	case v6 == 0:
+		return fmt.Errorf("%s only has IPv4 (A) addresses and none are reachable, check IPv4 connectivity or NAT64: %w", host, err)
+	}
+	return fmt.Errorf("none of the IPv4 or IPv6 addresses of %s are reachable: %w", host, err)
+}
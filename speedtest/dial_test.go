@@ -0,0 +1,111 @@
+package speedtest
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+// newAAAAOnlyResolver returns a resolver backed by a DNS server that answers
+// every AAAA query with ip and every other query with no records
+func newAAAAOnlyResolver(t *testing.T, ip net.IP) *net.Resolver {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := aaaaOnlyAnswer(buf[:n], ip); resp != nil {
+				pc.WriteTo(resp, addr)
+			}
+		}
+	}()
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", pc.LocalAddr().String())
+		},
+	}
+}
+
+// aaaaOnlyAnswer builds the response to a single question DNS query
+func aaaaOnlyAnswer(query []byte, ip net.IP) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+	end := 12
+	for end < len(query) && query[end] != 0 {
+		end += int(query[end]) + 1
+	}
+	end += 5 // root label, type and class
+	if end > len(query) {
+		return nil
+	}
+	qtype := binary.BigEndian.Uint16(query[end-4:])
+
+	resp := make([]byte, 12, 64)
+	copy(resp, query[:2])
+	binary.BigEndian.PutUint16(resp[2:], 0x8180) // response, recursion desired and available
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	resp = append(resp, query[12:end]...)
+	if qtype == 28 {
+		binary.BigEndian.PutUint16(resp[6:], 1)
+		resp = append(resp, 0xc0, 12, 0, 28, 0, 1, 0, 0, 0, 60, 0, 16)
+		resp = append(resp, ip.To16()...)
+	}
+	return resp
+}
+
+func TestDialAAAAOnlyHost(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback unavailable:", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	c := NewClient(Options{Resolver: newAAAAOnlyResolver(t, net.IPv6loopback)})
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	conn, err := c.dial(context.Background(), "tcp", net.JoinHostPort("aaaa-only.test", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if ip := conn.RemoteAddr().(*net.TCPAddr).IP; !ip.Equal(net.IPv6loopback) {
+		t.Errorf("connected to %v, want ::1", ip)
+	}
+}
+
+func TestDialAAAAOnlyHostUnreachable(t *testing.T) {
+	// Nothing listens on port 9 of the loopback address, so every dial fails
+	c := NewClient(Options{Resolver: newAAAAOnlyResolver(t, net.IPv6loopback)})
+
+	_, err := c.dial(context.Background(), "tcp", "aaaa-only.test:9")
+	if err == nil {
+		t.Fatal("dial succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "only has IPv6 (AAAA) addresses") {
+		t.Errorf("error %q does not explain that the host is IPv6 only", err)
+	}
+}
@@ -0,0 +1,51 @@
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+// DNSResult summarizes repeated lookups of the speed test host
+type DNSResult struct {
+	Host   string `json:"host"`
+	Probes int    `json:"probes"`
+	Failed int    `json:"failed"`
+
+	// Timing summarizes the successful lookups in ms
+	Timing Stats `json:"timing_ms"`
+}
+
+// ProbeDNS resolves hostname count times and summarizes how long each lookup
+// took. Lookups use Go's own resolver, which queries the configured name
+// servers directly instead of going through a caching layer such as nscd,
+// so each one reaches the resolver. That resolver may still answer from its
+// own cache; a slow first lookup followed by fast ones shows this.
+func (c *Client) ProbeDNS(ctx context.Context, hostname string, count int) (*DNSResult, error) {
+	resolver := c.resolver
+	if resolver == net.DefaultResolver {
+		resolver = &net.Resolver{PreferGo: true}
+	}
+
+	result := &DNSResult{Host: hostname, Probes: count}
+	var timings []float64
+	var lastErr error
+	for i := 0; i < count; i++ {
+		started := c.opts.Clock.Now()
+		_, err := resolver.LookupIPAddr(ctx, hostname)
+		if err != nil {
+			result.Failed++
+			lastErr = err
+			continue
+		}
+		timings = append(timings, c.opts.Clock.Now().Sub(started).Seconds()*1000)
+	}
+
+	if len(timings) == 0 && count > 0 {
+		return nil, errors.New("all lookups failed: " + lastErr.Error())
+	}
+	result.Timing = math.Summarize(timings)
+	return result, nil
+}
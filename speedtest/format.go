@@ -0,0 +1,43 @@
+package speedtest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String renders the headline results as the plain text summary the command
+// line tool prints by default, one "Name: value" line each, for logging a
+// result in one call
+func (r *Result) String() string {
+	var b strings.Builder
+	line := func(name, format string, args ...interface{}) {
+		fmt.Fprintf(&b, "%s: %s\n", name, fmt.Sprintf(format, args...))
+	}
+
+	if r.Timestamp != "" {
+		line("Test time", "%s", r.Timestamp)
+	}
+	if r.ServerColo != "" {
+		line("Server location", "%s (%s)", r.ServerCity, r.ServerColo)
+		line("Your IP", "%s (%s)", r.IP, r.Location)
+	}
+	line("Latency", "%.2f ms", r.Latency)
+	line("Jitter", "%.2f ms", r.Jitter)
+	line("TTFB", "%.2f ms", r.TTFB)
+	if r.LoadedLatency != nil {
+		line("Bufferbloat grade", "%s", r.LoadedLatency.Grade)
+	}
+	for _, tier := range r.Downloads {
+		line(tier.Label+" download speed", "%.2f Mbps", tier.Speed)
+	}
+	for _, tier := range r.Uploads {
+		line(tier.Label+" upload speed", "%.2f Mbps", tier.Speed)
+	}
+	line("Download speed", "%.2f Mbps", r.Download)
+	line("Upload speed", "%.2f Mbps", r.Upload)
+	line("Grade", "%s (%.0f/100)", r.Grade.Letter, r.Grade.Score)
+	for _, warning := range r.Warnings {
+		line("Warning", "%s", warning)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,80 @@
+package speedtest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+// HandshakeResult summarizes repeated connection setups without any transfer
+type HandshakeResult struct {
+	// Connect is the TCP connection time and Handshake the TLS handshake
+	// time that follows it, both in ms
+	Connect    Stats  `json:"connect_ms"`
+	Handshake  Stats  `json:"handshake_ms"`
+	TLSVersion string `json:"tls_version"`
+}
+
+// MeasureHandshakes opens count connections to the speed test host, completes
+// the TLS handshake and closes each without sending a request, isolating the
+// cost of connection setup from transfers. Connections go where requests
+// would, including a port in the host and the Unix socket if one is set.
+func (c *Client) MeasureHandshakes(ctx context.Context, count int) (*HandshakeResult, error) {
+	addr, serverName, err := c.handshakeAddr()
+	if err != nil {
+		return nil, err
+	}
+	config := c.newTransport().TLSClientConfig.Clone()
+	config.ServerName = serverName
+
+	var connects, handshakes []float64
+	var version uint16
+	for i := 0; i < count; i++ {
+		started := c.opts.Clock.Now()
+		conn, err := c.dial(ctx, "tcp", addr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		connected := c.opts.Clock.Now()
+
+		tlsConn := tls.Client(conn, config)
+		err = tlsConn.HandshakeContext(ctx)
+		handshakeDone := c.opts.Clock.Now()
+		if err != nil {
+			conn.Close()
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		version = tlsConn.ConnectionState().Version
+		tlsConn.Close()
+
+		connects = append(connects, connected.Sub(started).Seconds()*1000)
+		handshakes = append(handshakes, handshakeDone.Sub(connected).Seconds()*1000)
+	}
+
+	if len(handshakes) == 0 {
+		return nil, errors.New("all handshakes failed")
+	}
+	return &HandshakeResult{
+		Connect:    math.Summarize(connects),
+		Handshake:  math.Summarize(handshakes),
+		TLSVersion: TLSVersionName(version),
+	}, nil
+}
+
+// handshakeAddr returns the address connections for MeasureHandshakes are
+// made to and the TLS server name
+func (c *Client) handshakeAddr() (addr, serverName string, err error) {
+	addr, scheme := c.hostAddr()
+	if scheme == "http" {
+		return "", "", errors.New("the http scheme has no TLS handshake to measure")
+	}
+	serverName, _, err = net.SplitHostPort(addr)
+	return addr, serverName, err
+}
@@ -0,0 +1,30 @@
+package speedtest
+
+import "testing"
+
+func TestHandshakeAddr(t *testing.T) {
+	tests := []struct {
+		opts       Options
+		addr       string
+		serverName string
+		wantErr    bool
+	}{
+		{opts: Options{}, addr: "speed.cloudflare.com:443", serverName: "speed.cloudflare.com"},
+		{opts: Options{Host: "speed.example.com:8443"}, addr: "speed.example.com:8443", serverName: "speed.example.com"},
+		{opts: Options{Host: "[2001:db8::1]:8443"}, addr: "[2001:db8::1]:8443", serverName: "2001:db8::1"},
+		{opts: Options{Backend: &CloudflareBackend{Host: "backend.example.com"}}, addr: "backend.example.com:443", serverName: "backend.example.com"},
+		{opts: Options{Host: "localhost:8080", Scheme: "http"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		addr, serverName, err := NewClient(tt.opts).handshakeAddr()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%+v: got %s, want an error", tt.opts, addr)
+			}
+			continue
+		}
+		if err != nil || addr != tt.addr || serverName != tt.serverName {
+			t.Errorf("%+v: got %q, %q, %v, want %q, %q", tt.opts, addr, serverName, err, tt.addr, tt.serverName)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package speedtest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// trustedIssuers are the certificate authorities Cloudflare is known to use
+// for its edge certificates, matched against the issuer organization
+var trustedIssuers = []string{
+	"Cloudflare",
+	"Google Trust Services",
+	"Let's Encrypt",
+	"DigiCert",
+	"Sectigo",
+	"SSL Corporation",
+	"Baltimore",
+}
+
+// interceptionWarnings inspects a response from the trace endpoint for signs
+// that a captive portal or TLS-intercepting proxy answered instead of
+// Cloudflare. Interception makes every other measurement meaningless, so any
+// warning should be surfaced prominently. The TLS checks are skipped for
+// the http scheme, which is never served over TLS.
+func interceptionWarnings(resp *response, hostname, scheme string, trace map[string]string) []string {
+	var warnings []string
+
+	if scheme != "http" {
+		warnings = append(warnings, certificateWarnings(resp.tls)...)
+	}
+
+	if server := resp.header.Get("Server"); !strings.EqualFold(server, "cloudflare") {
+		warnings = append(warnings, fmt.Sprintf("Server header is %q, expected \"cloudflare\"", server))
+	}
+	if resp.header.Get("CF-RAY") == "" {
+		warnings = append(warnings, "response has no CF-RAY header")
+	}
+	if trace["colo"] == "" || trace["h"] != hostname {
+		warnings = append(warnings, "trace response does not match Cloudflare's format")
+	}
+	return warnings
+}
+
+// certificateWarnings checks that a response was served over TLS with a
+// certificate from a CA Cloudflare uses
+func certificateWarnings(state *tls.ConnectionState) []string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return []string{"response was not served over TLS"}
+	}
+	issuer := state.PeerCertificates[0].Issuer
+	for _, org := range issuer.Organization {
+		for _, known := range trustedIssuers {
+			if strings.Contains(org, known) {
+				return nil
+			}
+		}
+	}
+	return []string{fmt.Sprintf("certificate issued by %q, which is not a CA Cloudflare is known to use", issuer.String())}
+}
@@ -0,0 +1,20 @@
+package speedtest
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestInterceptionWarningsScheme(t *testing.T) {
+	resp := &response{header: http.Header{"Server": {"cloudflare"}, "Cf-Ray": {"8a1b2c3d4e5f-EWR"}}}
+	trace := map[string]string{"colo": "EWR", "h": "localhost:8080"}
+
+	if got := interceptionWarnings(resp, "localhost:8080", "http", trace); got != nil {
+		t.Errorf("http scheme: got warnings %q, want none", got)
+	}
+	want := []string{"response was not served over TLS"}
+	if got := interceptionWarnings(resp, "localhost:8080", "https", trace); !reflect.DeepEqual(got, want) {
+		t.Errorf("https scheme: got warnings %q, want %q", got, want)
+	}
+}
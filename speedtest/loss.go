@@ -0,0 +1,68 @@
+package speedtest
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/analysis"
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+// lossProbeTimeout bounds each loss probe so a dropped connection counts as a
+// failure quickly instead of waiting on the default timeouts
+const lossProbeTimeout = 2 * time.Second
+
+// LossResult summarizes a run of loss probes. Latency and Jitter are the
+// median and consecutive jitter of the successful probes in ms, and MOS the
+// voice call quality they predict together with the loss, see analysis.MOS.
+// All three are zero when every probe failed.
+type LossResult struct {
+	Probes      int     `json:"probes"`
+	Failed      int     `json:"failed"`
+	LossPercent float64 `json:"loss_percent"`
+	Latency     float64 `json:"latency"`
+	Jitter      float64 `json:"jitter"`
+	MOS         float64 `json:"mos"`
+}
+
+// ProbeLoss sends count minimal requests in quick succession without retries
+// and counts how many fail or time out. HTTP runs over TCP, so this does not
+// see packet loss directly; it is a rough indicator of connection-level loss.
+// The probes are timed too, for a mean opinion score that accounts for loss.
+func (c *Client) ProbeLoss(ctx context.Context, count int) *LossResult {
+	httpClient := c.newHTTPClient(lossProbeTimeout)
+
+	result := &LossResult{Probes: count}
+	var latencies []float64
+	for i := 0; i < count; i++ {
+		req, err := c.opts.Backend.Download(ctx, 0)
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		started := c.opts.Clock.Now()
+		resp, err := httpClient.Do(req)
+		if err == nil {
+			_, err = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if err == nil {
+				err = checkStatus(resp)
+			}
+		}
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		latencies = append(latencies, float64(c.opts.Clock.Now().Sub(started))/float64(time.Millisecond))
+	}
+	if count > 0 {
+		result.LossPercent = float64(result.Failed) / float64(count) * 100
+	}
+	if len(latencies) > 0 {
+		result.Latency = math.Median(latencies)
+		result.Jitter = math.ConsecutiveJitter(latencies)
+		result.MOS = analysis.MOS(result.Latency, result.Jitter, result.LossPercent)
+	}
+	return result
+}
@@ -0,0 +1,710 @@
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/analysis"
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+func measureSpeed(bytes int, duration time.Duration) float64 {
+	return float64(bytes*8) / (duration.Seconds() * 1e6)
+}
+
+// streamDownloadBytes is the size requested by each stream in fixed-duration
+// mode. Streams are reopened if one completes before the duration elapses.
+const streamDownloadBytes = 100001000
+
+// measureDownloadDuration downloads continuously until the duration elapses and
+// returns the throughput in Mbps computed from the bytes actually received.
+func (c *Client) measureDownloadDuration(ctx context.Context, duration time.Duration) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	httpClient := c.newHTTPClient(0)
+	buf := make([]byte, 32*1024)
+
+	var received int
+	var reading time.Duration
+	for ctx.Err() == nil {
+		req, err := c.opts.Backend.Download(ctx, streamDownloadBytes)
+		if err != nil {
+			return 0, err
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return 0, err
+		}
+		if err := checkStatus(resp); err != nil {
+			resp.Body.Close()
+			return 0, err
+		}
+
+		// Stop reading once the deadline cancels the body
+		body := c.limit(ctx, resp.Body)
+		started := c.opts.Clock.Now()
+		for {
+			n, err := body.Read(buf)
+			received += n
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					resp.Body.Close()
+					return 0, err
+				}
+				break
+			}
+		}
+		reading += c.opts.Clock.Now().Sub(started)
+		resp.Body.Close()
+	}
+
+	if received == 0 {
+		return 0, errors.New("no data received")
+	}
+	return measureSpeed(received, reading), nil
+}
+
+// streamSampleInterval is the window over which a single-stream download is sampled
+const streamSampleInterval = 250 * time.Millisecond
+
+// streamSample is the throughput of one window of a streamed download
+type streamSample struct {
+	end  time.Duration // since the response body started to be read
+	mbps float64
+}
+
+// streamSpeeds returns the throughput of each sample
+func streamSpeeds(samples []streamSample) []float64 {
+	speeds := make([]float64, len(samples))
+	for i, s := range samples {
+		speeds[i] = s.mbps
+	}
+	return speeds
+}
+
+// measureDownloadStream performs one large download and samples the
+// throughput of each streamSampleInterval window while it is read. This
+// reflects sustained throughput without the setup cost of many requests.
+func (c *Client) measureDownloadStream(ctx context.Context, bytes int) ([]streamSample, error) {
+	httpClient := c.newHTTPClient(0)
+	req, err := c.opts.Backend.Download(ctx, bytes)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var samples []streamSample
+	var received, windowBytes int
+	buf := make([]byte, 32*1024)
+	body := c.limit(ctx, resp.Body)
+	started := c.opts.Clock.Now()
+	windowStarted := started
+	for {
+		n, err := body.Read(buf)
+		received += n
+		windowBytes += n
+		if elapsed := c.opts.Clock.Now().Sub(windowStarted); elapsed >= streamSampleInterval {
+			samples = append(samples, streamSample{end: c.opts.Clock.Now().Sub(started), mbps: measureSpeed(windowBytes, elapsed)})
+			windowBytes = 0
+			windowStarted = c.opts.Clock.Now()
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Fall back to the whole transfer if it finished within a single window
+	if len(samples) == 0 && received > 0 {
+		elapsed := c.opts.Clock.Now().Sub(started)
+		samples = append(samples, streamSample{end: elapsed, mbps: measureSpeed(received, elapsed)})
+	}
+	return samples, nil
+}
+
+// latencyResult holds the outcome of the latency probes
+type latencyResult struct {
+	latency    math.Stats // TTFB minus server processing time
+	ttfb       math.Stats
+	tlsVersion uint16 // negotiated on the last successful probe
+
+	// samples are the latencies in probe order
+	samples []float64
+}
+
+// latencyProbes is the number of small downloads timed during a run
+const latencyProbes = 20
+
+// measureLatency times count small downloads. Up to LatencyConcurrency
+// probes run at once; running them concurrently finishes sooner but the
+// probes then compete for the link, which can inflate the measured RTT.
+// Fewer than count probes are used if LatencyBudget runs out.
+//
+// A warmup probe is sent first and discarded. The first request of a run
+// pays for DNS and connection setup and is consistently an outlier that
+// would inflate the mean; the connection it opens is then reused by the
+// probes. TCPLatency probes open their own connections regardless, so for
+// them the warmup only primes DNS caches.
+func (c *Client) measureLatency(ctx context.Context, count int) (*latencyResult, error) {
+	timings := make([]*requestTiming, count)
+	concurrency := c.opts.LatencyConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	probeCtx := ctx
+	if c.opts.LatencyBudget > 0 {
+		var cancel context.CancelFunc
+		probeCtx, cancel = context.WithTimeout(ctx, c.opts.LatencyBudget)
+		defer cancel()
+	}
+
+	// Warmup failures are left for the real probes to report
+	c.request(probeCtx, c.probeRequest, nil)
+
+	// Each probe writes only its own slot so results keep their probe order
+	errs := make([]error, count)
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range timings {
+		sem <- struct{}{}
+		if probeCtx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			timing, err := c.request(probeCtx, c.probeRequest, nil)
+			if err != nil {
+				// Probes cut off by the budget are expected, not errors
+				if probeCtx.Err() == nil {
+					errs[i] = err
+					if !c.aborts(err) {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					}
+				}
+				return
+			}
+			timings[i] = timing
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && c.aborts(err) {
+			return nil, err
+		}
+	}
+
+	var measurements, ttfbs []float64
+	var tlsVersion uint16
+	for _, timing := range timings {
+		if timing == nil {
+			continue
+		}
+
+		measurements = append(measurements, c.probeLatency(timing))
+		ttfbs = append(ttfbs, timing.ttfb.Sub(timing.started).Seconds()*1000)
+		tlsVersion = timing.tlsVersion
+	}
+
+	if len(measurements) == 0 {
+		return nil, errors.New("all latency probes failed")
+	}
+
+	return &latencyResult{
+		latency:    math.Summarize(measurements),
+		ttfb:       math.Summarize(ttfbs),
+		tlsVersion: tlsVersion,
+		samples:    measurements,
+	}, nil
+}
+
+// probeRequest builds a latency probe. Probes timing the TCP handshake close
+// their connection, which also sends each of them on a new one.
+func (c *Client) probeRequest(ctx context.Context, _ io.Reader) (*http.Request, error) {
+	req, err := c.opts.Backend.Download(ctx, c.opts.ProbeSize)
+	if err == nil && c.opts.LatencyMethod == TCPLatency {
+		req.Close = true
+	}
+	return req, err
+}
+
+// probeLatency is the latency measured by a probe using the configured
+// method. With TCPLatency every probe opens a new connection, so the TCP
+// handshake is always timed; a probe without one falls back to TTFB.
+func (c *Client) probeLatency(timing *requestTiming) float64 {
+	if c.opts.LatencyMethod == TCPLatency && !timing.tcpStart.IsZero() && timing.tcpHandshake.After(timing.tcpStart) {
+		return timing.tcpHandshake.Sub(timing.tcpStart).Seconds() * 1000
+	}
+	// TTFB - Server processing time
+	return timing.ttfb.Sub(timing.started).Seconds()*1000 - timing.serverTiming
+}
+
+// jitter computes jitter from the latency probes using the configured method
+func (c *Client) jitter(ping *latencyResult) float64 {
+	if c.opts.JitterMethod == Consecutive {
+		return math.ConsecutiveJitter(ping.samples)
+	}
+	return ping.latency.Jitter
+}
+
+// Ping measures latency with count small downloads, after a discarded warmup
+// probe, and returns statistics of the round trip time in milliseconds,
+// excluding server processing time
+func (c *Client) Ping(ctx context.Context, count int) (Stats, error) {
+	ping, err := c.measureLatency(ctx, count)
+	if err != nil {
+		return Stats{}, err
+	}
+	return ping.latency, nil
+}
+
+// downloadSamples holds the speed and time to first byte of each download
+type downloadSamples struct {
+	speeds   []float64
+	ttfbs    []float64 // ms
+	received []float64 // bytes actually read
+	failed   int
+
+	// discarded counts downloads shorter than Options.MinSampleDuration and
+	// longest is the longest request, for Options.FinishDuration
+	discarded int
+	longest   time.Duration
+}
+
+// measureDownload measures downloads of bytes as scheduled by sampler
+func (c *Client) measureDownload(ctx context.Context, bytes, iterations int, sampler Sampler) (downloadSamples, error) {
+	var samples downloadSamples
+	var mu sync.Mutex
+
+	err := sampler.Sample(ctx, iterations, func() ([]float64, error) {
+		timing, err := c.download(ctx, bytes)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if c.aborts(err) {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			samples.failed++
+			return append([]float64(nil), samples.speeds...), nil
+		}
+
+		if d := timing.ended.Sub(timing.started); d > samples.longest {
+			samples.longest = d
+		}
+
+		// The service may not return exactly the size requested, so the
+		// speed is computed from the bytes actually read
+		transferTime := timing.ended.Sub(timing.ttfb)
+		if transferTime < c.opts.MinSampleDuration {
+			samples.discarded++
+			return append([]float64(nil), samples.speeds...), nil
+		}
+		samples.speeds = append(samples.speeds, measureSpeed(int(timing.received), transferTime))
+		samples.received = append(samples.received, float64(timing.received))
+		samples.ttfbs = append(samples.ttfbs, timing.ttfb.Sub(timing.started).Seconds()*1000)
+		return append([]float64(nil), samples.speeds...), nil
+	})
+	return samples, err
+}
+
+// uploadSamples holds the speed of each upload measured both ways
+type uploadSamples struct {
+	server []float64
+	client []float64
+	failed int
+
+	// discarded and longest are as for downloadSamples
+	discarded int
+	longest   time.Duration
+}
+
+// reported returns the samples selected by the UploadTiming option
+func (s uploadSamples) reported(timing UploadTiming) []float64 {
+	if timing == ClientTiming {
+		return s.client
+	}
+	return s.server
+}
+
+// measureUpload measures uploads of bytes as scheduled by sampler, each both
+// from the server's reported processing time and from the time the client
+// saw between sending the body and the server responding
+func (c *Client) measureUpload(ctx context.Context, bytes, iterations int, sampler Sampler) (uploadSamples, error) {
+	var samples uploadSamples
+	var mu sync.Mutex
+
+	err := sampler.Sample(ctx, iterations, func() ([]float64, error) {
+		timing, err := c.upload(ctx, bytes)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if c.aborts(err) {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			samples.failed++
+			return append([]float64(nil), samples.reported(c.opts.UploadTiming)...), nil
+		}
+
+		if d := timing.ended.Sub(timing.started); d > samples.longest {
+			samples.longest = d
+		}
+		c.caveats.upload(timing.serverTiming > 0)
+
+		// The server responds once it has read the whole body, so the
+		// first response byte marks the end of the transfer. Finishing
+		// the write only means the body fit in the socket buffers.
+		clientTime := timing.ttfb.Sub(timing.bodyStarted)
+		serverTime := clientTime
+		if timing.serverTiming > 0 {
+			serverTime = time.Duration(timing.serverTiming * float64(time.Millisecond))
+		}
+		reportedTime := serverTime
+		if c.opts.UploadTiming == ClientTiming {
+			reportedTime = clientTime
+		}
+		if reportedTime < c.opts.MinSampleDuration {
+			samples.discarded++
+			return append([]float64(nil), samples.reported(c.opts.UploadTiming)...), nil
+		}
+		samples.server = append(samples.server, measureSpeed(bytes, serverTime))
+		samples.client = append(samples.client, measureSpeed(bytes, clientTime))
+		return append([]float64(nil), samples.reported(c.opts.UploadTiming)...), nil
+	})
+	return samples, err
+}
+
+// speedStats summarizes speed samples using the configured percentile
+func (c *Client) speedStats(samples []float64) SpeedStats {
+	summary := math.Summarize(samples)
+	return SpeedStats{
+		Min:        summary.Min,
+		Median:     summary.Median,
+		Percentile: math.Percentile(samples, c.opts.Percentile/100, c.opts.PercentileMethod),
+		Max:        summary.Max,
+	}
+}
+
+// percentiles computes each of the extra percentiles requested in Options
+func (c *Client) percentiles(samples []float64) []PercentileValue {
+	var values []PercentileValue
+	for _, p := range c.opts.Percentiles {
+		mbps := math.Percentile(samples, p/100, c.opts.PercentileMethod)
+		values = append(values, PercentileValue{
+			Percentile:     p,
+			Mbps:           mbps,
+			BytesPerSecond: BytesPerSecond(mbps),
+		})
+	}
+	return values
+}
+
+// SizeTier is a payload size and the number of times it is measured
+type SizeTier struct {
+	Label      string
+	Bytes      int
+	Iterations int
+}
+
+// DefaultDownloadTiers and DefaultUploadTiers are the sizes measured by a full test
+var (
+	DefaultDownloadTiers = []SizeTier{
+		{"100kB", 101000, 10},
+		{"1MB", 1001000, 8},
+		{"10MB", 10001000, 6},
+		{"25MB", 25001000, 4},
+		{"100MB", 100001000, 1},
+	}
+	DefaultUploadTiers = []SizeTier{
+		{"11kB", 11000, 10},
+		{"100kB", 101000, 10},
+		{"1MB", 1001000, 8},
+	}
+)
+
+// QuickDownloadTiers and QuickUploadTiers measure only small sizes a few
+// times, finishing in seconds at the cost of underestimating fast links
+var (
+	QuickDownloadTiers = []SizeTier{
+		{"100kB", 101000, 4},
+		{"1MB", 1001000, 2},
+	}
+	QuickUploadTiers = []SizeTier{
+		{"100kB", 101000, 4},
+		{"1MB", 1001000, 2},
+	}
+)
+
+// Run performs a full speed test: latency, server metadata, download and
+// upload. Requests share connections, and those left idle are closed when
+// it returns so that a caller running tests on an interval does not
+// accumulate them.
+func (c *Client) Run(ctx context.Context) (*Result, error) {
+	opts := c.opts
+	started := c.opts.Clock.Now()
+	defer c.transport.CloseIdleConnections()
+
+	c.tally = &connectionTally{}
+	c.redirects = &redirectLog{}
+	c.traffic = newTrafficTally()
+	c.breaker = &circuitBreaker{limit: opts.MaxConsecutiveFailures}
+	c.caveats = &caveatTally{}
+	c.traffic.start(phaseLatency)
+	ping, err := c.measureLatency(ctx, latencyProbes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure latency: %w", err)
+	}
+	latencyDone := c.opts.Clock.Now()
+
+	// Without the trace the server location and client IP stay empty
+	c.traffic.start(phaseMetadata)
+	trace := &TraceInfo{}
+	if !opts.NoTrace {
+		trace, err = c.Trace(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	metadataDone := c.opts.Clock.Now()
+
+	result := &Result{
+		ServerCity: trace.City,
+		ServerColo: trace.Colo,
+		IP:         trace.IP,
+		Location:   trace.Location,
+		Latency:    ping.latency.Median,
+		Jitter:     c.jitter(ping),
+		TTFB:       ping.ttfb.Median,
+
+		JitterMethod:  opts.JitterMethod,
+		LatencyMethod: opts.LatencyMethod,
+		LatencyMode:   math.Mode(ping.samples, opts.ModeBucket),
+		LatencyProbes: ping.latency.Count,
+
+		ServerTimingIgnored: opts.IgnoreServerTiming,
+
+		TLSVersion: TLSVersionName(ping.tlsVersion),
+		Started:    started,
+		Percentile: opts.Percentile,
+
+		PercentileMethod: opts.PercentileMethod,
+
+		Interception: trace.Warnings,
+	}
+
+	// Download tests
+	c.traffic.start(phaseDownload)
+	cpu := startCPUMeter(c.opts.Clock)
+	// Probes are stopped on return too, in case a measurement fails
+	var stopProbes func() []float64
+	if opts.LoadedLatency {
+		stopProbes = c.probeUnderLoad(ctx)
+		defer stopProbes()
+	}
+	var downloadTests []float64
+	if opts.Duration > 0 {
+		result.Download, err = c.measureDownloadDuration(ctx, opts.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s download: %w", opts.Duration, err)
+		}
+		downloadTests = []float64{result.Download}
+		result.DownloadStats = c.speedStats(downloadTests)
+	} else if opts.SingleStream {
+		stream, err := c.measureDownloadStream(ctx, streamDownloadBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure single-stream download: %w", err)
+		}
+		samples := streamSpeeds(stream)
+		stats := c.speedStats(samples)
+		result.Downloads = append(result.Downloads, TierResult{Label: "100MB stream", Bytes: streamDownloadBytes, Speed: stats.Median, Stats: stats})
+		result.DownloadStats = stats
+		result.Download = stats.Percentile
+		downloadTests = samples
+	} else {
+		var excluded map[int]bool
+		if opts.SkipSmallTiers && len(opts.DownloadTiers) > 0 {
+			excluded, err = c.smallTiers(ctx, opts.DownloadTiers)
+			if err != nil {
+				return nil, fmt.Errorf("failed to probe link speed: %w", err)
+			}
+		}
+
+		// Tiers after the one that finished the direction are not measured
+		measuredDownloads := opts.DownloadTiers
+		tierSamples := make([]downloadSamples, len(opts.DownloadTiers))
+		for i, tier := range opts.DownloadTiers {
+			tierSamples[i], err = c.measureDownload(ctx, tier.Bytes, tier.Iterations, opts.Sampler)
+			if err != nil {
+				return nil, fmt.Errorf("failed to measure %s download: %w", tier.Label, err)
+			}
+			if c.finished(tierSamples[i].longest) {
+				measuredDownloads = opts.DownloadTiers[:i+1]
+				break
+			}
+		}
+
+		// Best effort runs give the failed measurements a second chance
+		// once every tier has been tried
+		retried := make([]int, len(opts.DownloadTiers))
+		if !opts.FailFast {
+			for i, tier := range measuredDownloads {
+				retried[i] = tierSamples[i].failed
+				if retried[i] == 0 {
+					continue
+				}
+				samples, err := c.measureDownload(ctx, tier.Bytes, retried[i], FixedSampler{})
+				if err != nil {
+					return nil, fmt.Errorf("failed to measure %s download: %w", tier.Label, err)
+				}
+				tierSamples[i].speeds = append(tierSamples[i].speeds, samples.speeds...)
+				tierSamples[i].ttfbs = append(tierSamples[i].ttfbs, samples.ttfbs...)
+				tierSamples[i].received = append(tierSamples[i].received, samples.received...)
+				tierSamples[i].failed = samples.failed
+				tierSamples[i].discarded += samples.discarded
+			}
+		}
+
+		for i, tier := range measuredDownloads {
+			samples := tierSamples[i]
+			stats := c.speedStats(samples.speeds)
+			result.Downloads = append(result.Downloads, TierResult{Label: tier.Label, Bytes: tier.Bytes, Speed: stats.Median, Stats: stats,
+				TTFB: math.Median(samples.ttfbs), Received: int64(math.Median(samples.received)),
+				Retried: retried[i], Failed: samples.failed, Discarded: samples.discarded, Samples: c.sampleCount(samples.speeds), Excluded: excluded[i]})
+			if !excluded[i] {
+				downloadTests = append(downloadTests, samples.speeds...)
+			}
+		}
+		if len(downloadTests) == 0 && len(opts.DownloadTiers) > 0 {
+			return nil, errors.New("all download measurements failed")
+		}
+		result.DownloadStats = c.speedStats(downloadTests)
+		result.Download = result.DownloadStats.Percentile
+	}
+	result.DownloadPercentiles = c.percentiles(downloadTests)
+	result.DownloadStability = analysis.StabilityOf(math.CoefficientOfVariation(downloadTests))
+
+	if percent, ok := cpu.percent(); ok {
+		result.DownloadCPU = percent
+		result.CPUBound = percent >= cpuBoundPercent
+	}
+
+	var downloadLoaded []float64
+	if stopProbes != nil {
+		downloadLoaded = stopProbes()
+	}
+	downloadDone := c.opts.Clock.Now()
+
+	// Upload tests
+	c.traffic.start(phaseUpload)
+	if opts.LoadedLatency || opts.UploadLoadedLatency {
+		stopProbes = c.probeUnderLoad(ctx)
+		defer stopProbes()
+	}
+	measuredUploads := opts.UploadTiers
+	uploadTiers := make([]uploadSamples, len(opts.UploadTiers))
+	for i, tier := range opts.UploadTiers {
+		uploadTiers[i], err = c.measureUpload(ctx, tier.Bytes, tier.Iterations, opts.Sampler)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s upload: %w", tier.Label, err)
+		}
+		if c.finished(uploadTiers[i].longest) {
+			measuredUploads = opts.UploadTiers[:i+1]
+			break
+		}
+	}
+
+	uploadRetried := make([]int, len(opts.UploadTiers))
+	if !opts.FailFast {
+		for i, tier := range measuredUploads {
+			uploadRetried[i] = uploadTiers[i].failed
+			if uploadRetried[i] == 0 {
+				continue
+			}
+			samples, err := c.measureUpload(ctx, tier.Bytes, uploadRetried[i], FixedSampler{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to measure %s upload: %w", tier.Label, err)
+			}
+			uploadTiers[i].server = append(uploadTiers[i].server, samples.server...)
+			uploadTiers[i].client = append(uploadTiers[i].client, samples.client...)
+			uploadTiers[i].failed = samples.failed
+			uploadTiers[i].discarded += samples.discarded
+		}
+	}
+
+	var uploadTests uploadSamples
+	for i, tier := range measuredUploads {
+		samples := uploadTiers[i]
+		stats := c.speedStats(samples.reported(opts.UploadTiming))
+		result.Uploads = append(result.Uploads, TierResult{Label: tier.Label, Bytes: tier.Bytes, Speed: stats.Median, Stats: stats,
+			Retried: uploadRetried[i], Failed: samples.failed, Discarded: samples.discarded, Samples: c.sampleCount(samples.server)})
+		uploadTests.server = append(uploadTests.server, samples.server...)
+		uploadTests.client = append(uploadTests.client, samples.client...)
+	}
+	if len(uploadTests.server) == 0 && len(opts.UploadTiers) > 0 {
+		return nil, errors.New("all upload measurements failed")
+	}
+	result.UploadStats = c.speedStats(uploadTests.reported(opts.UploadTiming))
+	result.UploadPercentiles = c.percentiles(uploadTests.reported(opts.UploadTiming))
+	result.Upload = result.UploadStats.Percentile
+	result.UploadTiming = opts.UploadTiming
+	result.UploadServer = c.speedStats(uploadTests.server).Percentile
+	result.UploadClient = c.speedStats(uploadTests.client).Percentile
+	if stopProbes != nil {
+		result.LoadedLatency = newLoadedLatency(result.Latency, downloadLoaded, stopProbes())
+	}
+	uploadDone := c.opts.Clock.Now()
+
+	result.Timings = PhaseTimings{
+		Latency:  latencyDone.Sub(started).Seconds(),
+		Metadata: metadataDone.Sub(latencyDone).Seconds(),
+		Download: downloadDone.Sub(metadataDone).Seconds(),
+		Upload:   uploadDone.Sub(downloadDone).Seconds(),
+		Total:    uploadDone.Sub(started).Seconds(),
+	}
+	result.setBytesPerSecond()
+	result.Warnings = c.warnings(result)
+	result.Redirects = c.redirects.list()
+	result.Reliability = c.tally.reliability()
+	result.Traffic = c.traffic.traffic()
+
+	metrics := analysis.Metrics{
+		Download: result.Download,
+		Upload:   result.Upload,
+		Latency:  result.Latency,
+		Jitter:   result.Jitter,
+	}
+	result.AIM = analysis.AIM(metrics)
+	result.Grade = analysis.ConnectionGrade(metrics)
+	result.BDP = analysis.BandwidthDelayProduct(result.Download, result.Latency)
+
+	return result, nil
+}
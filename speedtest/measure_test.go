@@ -0,0 +1,299 @@
+package speedtest
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newPacedServer starts a server whose /__up endpoint reads request bodies
+// no faster than mbps and responds once the whole body has been read, with
+// serverTiming as its Server-Timing header when set
+func newPacedServer(t *testing.T, mbps float64, serverTiming string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 16*1024)
+		start := time.Now()
+		var read int
+		for {
+			n, err := r.Body.Read(buf)
+			read += n
+			due := start.Add(time.Duration(float64(read*8) / (mbps * 1e6) * float64(time.Second)))
+			time.Sleep(time.Until(due))
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return
+			}
+		}
+		if serverTiming != "" {
+			w.Header().Set("Server-Timing", serverTiming)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func assertSpeed(t *testing.T, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want)/want > 0.15 {
+		t.Errorf("measured %.2f Mbps, want %.2f ±15%%", got, want)
+	}
+}
+
+func TestClientUploadTimingPacedServer(t *testing.T) {
+	const mbps = 40
+	srv := newPacedServer(t, mbps, "")
+	c := NewClient(Options{
+		Host:         srv.Listener.Addr().String(),
+		Scheme:       "http",
+		UploadTiming: ClientTiming,
+	})
+
+	for _, bytes := range []int{100_000, 1_000_000} {
+		samples, err := c.measureUpload(context.Background(), bytes, 1, FixedSampler{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(samples.client) != 1 {
+			t.Fatalf("%d bytes: got %d client samples, want 1", bytes, len(samples.client))
+		}
+		assertSpeed(t, samples.client[0], mbps)
+	}
+}
+
+func TestIgnoreServerTimingUploadSpeed(t *testing.T) {
+	const mbps = 40
+	// A Server-Timing duration of 1ms would report a 1MB upload at 8000 Mbps
+	srv := newPacedServer(t, mbps, "cfRequestDuration;dur=1")
+	c := NewClient(Options{
+		Host:               srv.Listener.Addr().String(),
+		Scheme:             "http",
+		IgnoreServerTiming: true,
+	})
+
+	samples, err := c.measureUpload(context.Background(), 1_000_000, 1, FixedSampler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reported := samples.reported(c.opts.UploadTiming)
+	if len(reported) != 1 {
+		t.Fatalf("got %d samples, want 1", len(reported))
+	}
+	assertSpeed(t, reported[0], mbps)
+	assertSpeed(t, samples.server[0], mbps)
+}
+
+func TestUploadWithoutServerTimingFallsBackToClient(t *testing.T) {
+	const mbps = 40
+	srv := newPacedServer(t, mbps, "")
+	c := NewClient(Options{Host: srv.Listener.Addr().String(), Scheme: "http"})
+
+	samples, err := c.measureUpload(context.Background(), 1_000_000, 1, FixedSampler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples.server) != 1 {
+		t.Fatalf("got %d samples, want 1", len(samples.server))
+	}
+	assertSpeed(t, samples.server[0], mbps)
+	if c.caveats.noServerTiming != 1 {
+		t.Errorf("noServerTiming = %d, want 1", c.caveats.noServerTiming)
+	}
+}
+
+// newMockServer starts a server implementing the download and upload endpoints
+func newMockServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/__down", func(w http.ResponseWriter, r *http.Request) {
+		bytes, _ := strconv.Atoi(r.URL.Query().Get("bytes"))
+		w.Write(make([]byte, bytes))
+	})
+	mux.HandleFunc("/__up", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// mockOptions measures against srv with a single small tier each way
+func mockOptions(srv *httptest.Server) Options {
+	return Options{
+		Host:          srv.Listener.Addr().String(),
+		Scheme:        "http",
+		NoTrace:       true,
+		DownloadTiers: []SizeTier{{Label: "10kB", Bytes: 10_000, Iterations: 2}},
+		UploadTiers:   []SizeTier{{Label: "10kB", Bytes: 10_000, Iterations: 2}},
+	}
+}
+
+func TestRunTwiceReliability(t *testing.T) {
+	c := NewClient(mockOptions(newMockServer(t)))
+
+	first, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Reliability.Attempts == 0 {
+		t.Fatal("no requests were counted")
+	}
+	if second.Reliability != first.Reliability {
+		t.Errorf("second run reliability = %+v, want %+v as in the first", second.Reliability, first.Reliability)
+	}
+}
+
+func TestRunTwiceRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/__down", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved?"+r.URL.RawQuery, http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		bytes, _ := strconv.Atoi(r.URL.Query().Get("bytes"))
+		w.Write(make([]byte, bytes))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	opts := mockOptions(srv)
+	opts.UploadTiers = []SizeTier{}
+	c := NewClient(opts)
+
+	first, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Redirects) == 0 {
+		t.Fatal("no redirects were recorded")
+	}
+	if len(second.Redirects) != len(first.Redirects) {
+		t.Errorf("second run recorded %d redirects, want %d as in the first", len(second.Redirects), len(first.Redirects))
+	}
+}
+
+func TestRunReusesAndClosesConnections(t *testing.T) {
+	mux := http.NewServeMux()
+	var requests int
+	var mu sync.Mutex
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		if r.URL.Path == "/__down" {
+			bytes, _ := strconv.Atoi(r.URL.Query().Get("bytes"))
+			w.Write(make([]byte, bytes))
+		}
+		io.Copy(io.Discard, r.Body)
+	})
+	srv := httptest.NewUnstartedServer(mux)
+	var opened, closed int
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch state {
+		case http.StateNew:
+			opened++
+		case http.StateClosed:
+			closed++
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	if _, err := NewClient(mockOptions(srv)).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	if opened >= requests {
+		t.Errorf("%d requests opened %d connections, want them reused", requests, opened)
+	}
+	mu.Unlock()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		open := opened - closed
+		mu.Unlock()
+		if open == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d connections still open after Run returned", open)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTCPLatencyProbesUseNewConnections(t *testing.T) {
+	opts := mockOptions(newMockServer(t))
+	opts.LatencyMethod = TCPLatency
+	c := NewClient(opts)
+
+	for i := 0; i < 3; i++ {
+		timing, err := c.request(context.Background(), c.probeRequest, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if timing.tcpStart.IsZero() {
+			t.Fatalf("probe %d reused a connection, want its TCP handshake timed", i)
+		}
+	}
+}
+
+func TestLatencyWarmupOpensProbeConnection(t *testing.T) {
+	var opened int
+	var mu sync.Mutex
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			opened++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	opts := mockOptions(srv)
+	opts.LatencyConcurrency = 1
+	if _, err := NewClient(opts).Ping(context.Background(), 5); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if opened != 1 {
+		t.Errorf("warmup and probes opened %d connections, want the probes to reuse the warmup's", opened)
+	}
+}
+
+func TestRunReportsBytesPerSecond(t *testing.T) {
+	result, err := NewClient(mockOptions(newMockServer(t))).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := []SpeedStats{result.DownloadStats, result.UploadStats, result.Downloads[0].Stats, result.Uploads[0].Stats}
+	for _, s := range stats {
+		if s.Median == 0 || s.MedianBytesPerSecond != BytesPerSecond(s.Median) || s.MaxBytesPerSecond != BytesPerSecond(s.Max) {
+			t.Errorf("stats %+v lack bytes per second counterparts", s)
+		}
+	}
+	if result.UploadClientBytesPerSecond != BytesPerSecond(result.UploadClient) {
+		t.Errorf("UploadClientBytesPerSecond = %v, want %v", result.UploadClientBytesPerSecond, BytesPerSecond(result.UploadClient))
+	}
+}
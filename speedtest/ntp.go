@@ -0,0 +1,109 @@
+package speedtest
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultNTPServer is queried by CheckClock when no server is given
+const DefaultNTPServer = "time.cloudflare.com"
+
+// ntpTimeout limits a clock check when the context has no deadline
+const ntpTimeout = 5 * time.Second
+
+// ntpEpochOffset is the number of seconds from the NTP epoch, 1900, to the
+// Unix epoch
+const ntpEpochOffset = 2208988800
+
+// ClockOffset is how far the local clock is from an NTP server, positive
+// when the local clock is behind
+type ClockOffset struct {
+	Server string  `json:"server"`
+	Offset float64 `json:"offset_ms"`
+
+	// RoundTrip is the network delay of the query, which bounds the
+	// accuracy of Offset to about half of it
+	RoundTrip float64 `json:"round_trip_ms"`
+}
+
+// CheckClock queries server, DefaultNTPServer when empty, with a single SNTP
+// request and returns the offset of the local clock. The clock is only
+// measured, never adjusted.
+func CheckClock(ctx context.Context, server string) (*ClockOffset, error) {
+	if server == "" {
+		server = DefaultNTPServer
+	}
+	addr := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		addr = net.JoinHostPort(server, "123")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(ntpTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	// Version 3 client request, with the transmit time echoed back by the
+	// server as the originate time so the reply can be matched to it
+	request := make([]byte, 48)
+	request[0] = 3<<3 | 3
+	sent := time.Now()
+	binary.BigEndian.PutUint64(request[40:], ntpTime(sent))
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, 48)
+	n, err := conn.Read(reply)
+	if err != nil {
+		return nil, err
+	}
+	arrived := time.Now()
+	switch {
+	case n < len(reply):
+		return nil, errors.New("short NTP reply")
+	case reply[0]&7 != 4:
+		return nil, fmt.Errorf("unexpected NTP mode %d", reply[0]&7)
+	case reply[1] == 0:
+		return nil, errors.New("NTP server refused the request")
+	case binary.BigEndian.Uint64(reply[24:]) != binary.BigEndian.Uint64(request[40:]):
+		return nil, errors.New("NTP reply does not match the request")
+	}
+
+	received := fromNTPTime(binary.BigEndian.Uint64(reply[32:]))
+	transmitted := fromNTPTime(binary.BigEndian.Uint64(reply[40:]))
+	offset := (received.Sub(sent) + transmitted.Sub(arrived)) / 2
+	roundTrip := arrived.Sub(sent) - transmitted.Sub(received)
+
+	return &ClockOffset{
+		Server:    server,
+		Offset:    offset.Seconds() * 1000,
+		RoundTrip: roundTrip.Seconds() * 1000,
+	}, nil
+}
+
+// ntpTime converts t to a 64 bit NTP timestamp, seconds since 1900 in the
+// upper half and the fraction of a second in the lower
+func ntpTime(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / 1e9
+	return seconds<<32 | fraction
+}
+
+// fromNTPTime converts a 64 bit NTP timestamp to a time
+func fromNTPTime(ts uint64) time.Time {
+	seconds := int64(ts>>32) - ntpEpochOffset
+	nanos := int64((ts & 0xffffffff) * 1e9 >> 32)
+	return time.Unix(seconds, nanos)
+}
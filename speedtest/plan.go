@@ -0,0 +1,183 @@
+package speedtest
+
+import "time"
+
+// metadataTimeout is the per-attempt timeout of the trace and locations lookups
+const metadataTimeout = 30 * time.Second
+
+// PlanPhase estimates the requests, data and time of one phase of a run, on
+// the nominal path and in the worst case where every request is retried
+type PlanPhase struct {
+	Name          string  `json:"name"`
+	Requests      int     `json:"requests"`
+	Bytes         int64   `json:"bytes"`
+	Seconds       float64 `json:"seconds"`
+	WorstRequests int     `json:"worst_requests"`
+	WorstBytes    int64   `json:"worst_bytes"`
+	WorstSeconds  float64 `json:"worst_seconds"`
+}
+
+// add accumulates another phase into p
+func (p *PlanPhase) add(o PlanPhase) {
+	p.Requests += o.Requests
+	p.Bytes += o.Bytes
+	p.Seconds += o.Seconds
+	p.WorstRequests += o.WorstRequests
+	p.WorstBytes += o.WorstBytes
+	p.WorstSeconds += o.WorstSeconds
+}
+
+// Plan estimates a run without making any requests
+type Plan struct {
+	// AssumedMbps is the link speed transfer times are estimated at. Round
+	// trips are not included, so small transfers finish later than estimated.
+	AssumedMbps float64     `json:"assumed_mbps"`
+	Phases      []PlanPhase `json:"phases"`
+	Total       PlanPhase   `json:"total"`
+}
+
+// Plan estimates the requests, data and time of Run on a link of the given
+// speed in Mbps. The worst case assumes every request that can be retried
+// fails MaxRetries times, waiting the longest possible backoff each time,
+// that best effort runs repeat every measurement in their second pass, that
+// requests with a timeout run into it and that a StableSampler never finds
+// the speed stable. Other custom samplers are assumed to take each tier's
+// nominal count.
+func (c *Client) Plan(assumedMbps float64) *Plan {
+	opts := c.opts
+	bytesPerSecond := assumedMbps * 1e6 / 8
+	attempts := opts.MaxRetries + 1
+	backoff := maxRetryWait(opts.MaxRetries).Seconds()
+	transfer := func(bytes int64) float64 {
+		return float64(bytes) / bytesPerSecond
+	}
+
+	// measured builds the phase for requests made through request, which
+	// retries, optionally repeated by the best effort second pass. most
+	// are the requests and bytes before any retry in the worst case.
+	measured := func(name string, requests, most requestLoad, secondPass bool) PlanPhase {
+		passes := 1
+		if secondPass && !opts.FailFast {
+			passes = 2
+		}
+		worstRequests := most.requests * attempts * passes
+		worstBytes := most.bytes * int64(attempts*passes)
+		return PlanPhase{
+			Name:          name,
+			Requests:      requests.requests,
+			Bytes:         requests.bytes,
+			Seconds:       transfer(requests.bytes),
+			WorstRequests: worstRequests,
+			WorstBytes:    worstBytes,
+			WorstSeconds:  transfer(worstBytes) + float64(most.requests*passes)*backoff,
+		}
+	}
+
+	plan := &Plan{AssumedMbps: assumedMbps}
+
+	probes := latencyProbes + 1 // including the warmup
+	probeLoad := requestLoad{probes, int64(probes * opts.ProbeSize)}
+	latency := measured("latency", probeLoad, probeLoad, false)
+	if opts.LatencyBudget > 0 && latency.WorstSeconds > opts.LatencyBudget.Seconds() {
+		latency.WorstSeconds = opts.LatencyBudget.Seconds()
+	}
+	plan.Phases = append(plan.Phases, latency)
+
+	if !opts.NoTrace {
+		metadata := measured("metadata", requestLoad{requests: 2}, requestLoad{requests: 2}, false)
+		metadata.WorstSeconds = float64(2*attempts)*metadataTimeout.Seconds() + 2*backoff
+		plan.Phases = append(plan.Phases, metadata)
+	}
+
+	var download PlanPhase
+	switch {
+	case opts.Duration > 0:
+		// Streams are not retried and stop when the duration elapses
+		bytes := int64(bytesPerSecond * opts.Duration.Seconds())
+		download = PlanPhase{
+			Name: "download", Requests: 1, Bytes: bytes, Seconds: opts.Duration.Seconds(),
+			WorstRequests: 1, WorstBytes: bytes, WorstSeconds: opts.Duration.Seconds(),
+		}
+	case opts.SingleStream:
+		bytes := int64(streamDownloadBytes)
+		download = PlanPhase{
+			Name: "download", Requests: 1, Bytes: bytes, Seconds: transfer(bytes),
+			WorstRequests: 1, WorstBytes: bytes, WorstSeconds: transfer(bytes),
+		}
+	default:
+		if opts.SkipSmallTiers && len(opts.DownloadTiers) > 0 {
+			probe := requestLoad{1, fastLinkProbeBytes}
+			plan.Phases = append(plan.Phases, measured("link probe", probe, probe, false))
+		}
+		nominal, most := tierTotals(opts.DownloadTiers, opts.Sampler)
+		download = measured("download", nominal, most, true)
+	}
+	plan.Phases = append(plan.Phases, download)
+
+	nominal, most := tierTotals(opts.UploadTiers, opts.Sampler)
+	upload := measured("upload", nominal, most, true)
+	plan.Phases = append(plan.Phases, upload)
+
+	// Loaded latency probes run alongside the transfers, so they add
+	// requests and bytes but no time. They are not retried.
+	loaded := PlanPhase{Name: "loaded latency"}
+	addProbes := func(transfers PlanPhase) {
+		loaded.Requests += loadedProbes(transfers.Seconds)
+		loaded.WorstRequests += loadedProbes(transfers.WorstSeconds)
+	}
+	if opts.LoadedLatency {
+		addProbes(download)
+	}
+	if opts.LoadedLatency || opts.UploadLoadedLatency {
+		addProbes(upload)
+		loaded.Bytes = int64(loaded.Requests * opts.ProbeSize)
+		loaded.WorstBytes = int64(loaded.WorstRequests * opts.ProbeSize)
+		plan.Phases = append(plan.Phases, loaded)
+	}
+
+	plan.Total.Name = "total"
+	for _, phase := range plan.Phases {
+		plan.Total.add(phase)
+	}
+	return plan
+}
+
+// loadedProbes is the most latency probes sent while transferring for the
+// given number of seconds, one immediately and then one every interval
+func loadedProbes(seconds float64) int {
+	return int(seconds/loadedProbeInterval.Seconds()) + 1
+}
+
+// requestLoad is a number of requests and the bytes they transfer
+type requestLoad struct {
+	requests int
+	bytes    int64
+}
+
+// tierTotals returns the requests and bytes of measuring tiers, at their
+// nominal counts and at the most sampler can take
+func tierTotals(tiers []SizeTier, sampler Sampler) (nominal, most requestLoad) {
+	for _, tier := range tiers {
+		iterations := tier.Iterations
+		if s, ok := sampler.(StableSampler); ok {
+			iterations = s.limit(tier.Iterations)
+		}
+		nominal.requests += tier.Iterations
+		nominal.bytes += int64(tier.Bytes) * int64(tier.Iterations)
+		most.requests += iterations
+		most.bytes += int64(tier.Bytes) * int64(iterations)
+	}
+	return nominal, most
+}
+
+// maxRetryWait is the longest total backoff withRetries can wait for one
+// request across the given number of retries
+func maxRetryWait(retries int) time.Duration {
+	var total time.Duration
+	delay := retryBackoff
+	for i := 0; i < retries; i++ {
+		total += delay/2 + delay
+		delay *= 2
+	}
+	return total
+}
@@ -0,0 +1,75 @@
+package speedtest
+
+import "testing"
+
+// planPhase returns the phase of p with the given name
+func planPhase(t *testing.T, p *Plan, name string) PlanPhase {
+	t.Helper()
+	for _, phase := range p.Phases {
+		if phase.Name == name {
+			return phase
+		}
+	}
+	t.Fatalf("plan has no %s phase", name)
+	return PlanPhase{}
+}
+
+func TestPlanUntilStable(t *testing.T) {
+	opts := Options{
+		NoTrace:       true,
+		DownloadTiers: []SizeTier{{Label: "1MB", Bytes: 1_000_000, Iterations: 4}, {Label: "100MB", Bytes: 100_000_000, Iterations: 1}},
+		UploadTiers:   []SizeTier{},
+	}
+	fixed := planPhase(t, NewClient(opts).Plan(100), "download")
+	opts.UntilStable = 0.05
+	stable := planPhase(t, NewClient(opts).Plan(100), "download")
+
+	if stable.Requests != fixed.Requests || stable.Bytes != fixed.Bytes {
+		t.Errorf("nominal %d requests and %d bytes, want %d and %d as without UntilStable",
+			stable.Requests, stable.Bytes, fixed.Requests, fixed.Bytes)
+	}
+	// The 1MB tier may take three times its 4 iterations, the 100MB tier
+	// keeps its single one, and the second pass can repeat all of them
+	if want := (4*3 + 1) * 2; stable.WorstRequests != want {
+		t.Errorf("worst case %d requests, want %d", stable.WorstRequests, want)
+	}
+	if want := int64(4*3*1_000_000+100_000_000) * 2; stable.WorstBytes != want {
+		t.Errorf("worst case %d bytes, want %d", stable.WorstBytes, want)
+	}
+}
+
+func TestPlanProbes(t *testing.T) {
+	opts := Options{
+		NoTrace:        true,
+		SkipSmallTiers: true,
+		LoadedLatency:  true,
+		DownloadTiers:  []SizeTier{{Label: "10MB", Bytes: 10_000_000, Iterations: 2}},
+		UploadTiers:    []SizeTier{{Label: "10MB", Bytes: 10_000_000, Iterations: 1}},
+	}
+	plan := NewClient(opts).Plan(100)
+
+	probe := planPhase(t, plan, "link probe")
+	if probe.Requests != 1 || probe.Bytes != fastLinkProbeBytes {
+		t.Errorf("link probe makes %d requests of %d bytes, want 1 of %d", probe.Requests, probe.Bytes, fastLinkProbeBytes)
+	}
+
+	// 1.6s of downloads and 0.8s of uploads at 100 Mbps, probed every 200ms
+	loaded := planPhase(t, plan, "loaded latency")
+	if want := 9 + 5; loaded.Requests != want {
+		t.Errorf("loaded latency makes %d requests, want %d", loaded.Requests, want)
+	}
+	if loaded.Bytes != int64(loaded.Requests*1000) || loaded.Seconds != 0 {
+		t.Errorf("loaded latency transfers %d bytes over %gs, want %d bytes alongside the transfers", loaded.Bytes, loaded.Seconds, loaded.Requests*1000)
+	}
+	if loaded.WorstRequests < loaded.Requests {
+		t.Errorf("worst case %d loaded latency requests, fewer than the nominal %d", loaded.WorstRequests, loaded.Requests)
+	}
+
+	var total int
+	for _, phase := range plan.Phases {
+		total += phase.Requests
+	}
+	if plan.Total.Requests != total {
+		t.Errorf("total is %d requests, want the %d of all phases", plan.Total.Requests, total)
+	}
+}
@@ -0,0 +1,61 @@
+package speedtest
+
+import (
+	"context"
+
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+// rampUpThreshold is the share of the steady-state rate at which a transfer
+// counts as ramped up
+const rampUpThreshold = 0.9
+
+// RampUpSample is the throughput of one sampling window of the transfer
+type RampUpSample struct {
+	Seconds float64 `json:"seconds"` // end of the window since the body started
+	Mbps    float64 `json:"mbps"`
+}
+
+// RampUpResult describes how a single large download accelerated to its
+// sustained rate, revealing TCP slow start and other ramp-up behavior
+type RampUpResult struct {
+	// SteadyState is the median rate over the second half of the transfer,
+	// by which point slow start is long over
+	SteadyState float64 `json:"steady_state_mbps"`
+
+	// RampUp is how long it took to first reach 90% of SteadyState, in
+	// seconds. It is the whole transfer if that never happened.
+	RampUp float64 `json:"ramp_up_s"`
+
+	Samples []RampUpSample `json:"samples"`
+}
+
+// MeasureRampUp samples the throughput of one large download every 250ms
+// and reports how long it took to reach its steady-state rate
+func (c *Client) MeasureRampUp(ctx context.Context) (*RampUpResult, error) {
+	samples, err := c.measureDownloadStream(ctx, streamDownloadBytes)
+	if err != nil {
+		return nil, err
+	}
+	return rampUp(samples), nil
+}
+
+func rampUp(samples []streamSample) *RampUpResult {
+	result := &RampUpResult{}
+	for _, s := range samples {
+		result.Samples = append(result.Samples, RampUpSample{Seconds: s.end.Seconds(), Mbps: s.mbps})
+	}
+	if len(samples) == 0 {
+		return result
+	}
+
+	result.SteadyState = math.Median(streamSpeeds(samples[len(samples)/2:]))
+	result.RampUp = samples[len(samples)-1].end.Seconds()
+	for _, s := range samples {
+		if s.mbps >= result.SteadyState*rampUpThreshold {
+			result.RampUp = s.end.Seconds()
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package speedtest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRateLimited is returned when the server rate limits a request and
+// Options.AbortOnRateLimit is set. Measurements taken while throttled would
+// describe the limit rather than the connection.
+var ErrRateLimited = errors.New("rate limited by the server")
+
+// maxRateLimitWait caps how long a Retry-After header can make a retry wait
+const maxRateLimitWait = time.Minute
+
+// retryAfter parses a Retry-After header, either a number of seconds or an
+// HTTP date, returning 0 when it is missing or invalid
+func retryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// rateLimitExhausted reports whether a successful response says no requests
+// are left in the current rate limit window, from the draft standard
+// RateLimit-Remaining header or the common X-RateLimit-Remaining
+func rateLimitExhausted(header http.Header) bool {
+	for _, name := range []string{"RateLimit-Remaining", "X-RateLimit-Remaining"} {
+		if remaining := strings.TrimSpace(header.Get(name)); remaining != "" {
+			return remaining == "0"
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package speedtest
+
+import (
+	"net/http"
+	"sync"
+)
+
+// connectionTally counts the requests made during a run and how many of
+// them failed before a response was received
+type connectionTally struct {
+	mu       sync.Mutex
+	attempts int
+	failed   int
+}
+
+func (t *connectionTally) record(failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attempts++
+	if failed {
+		t.failed++
+	}
+}
+
+func (t *connectionTally) reliability() Reliability {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := Reliability{Attempts: t.attempts, Failed: t.failed}
+	if t.attempts > 0 {
+		r.SuccessPercent = float64(t.attempts-t.failed) / float64(t.attempts) * 100
+	}
+	return r
+}
+
+// countingTransport records the outcome of every round trip in a tally and
+// the traffic it causes
+type countingTransport struct {
+	base    http.RoundTripper
+	tally   *connectionTally
+	traffic *trafficTally
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var sent int64
+	if req.ContentLength > 0 {
+		sent = req.ContentLength
+	}
+	phase := t.traffic.request(sent)
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		resp.Body = newCountingBody(resp.Body, t.traffic, phase)
+	}
+	// Requests cut short by their own deadline or cancellation are
+	// deliberate, not connection failures
+	if err != nil && req.Context().Err() != nil {
+		return resp, err
+	}
+	t.tally.record(err != nil)
+	return resp, err
+}
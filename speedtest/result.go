@@ -0,0 +1,295 @@
+package speedtest
+
+import (
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/analysis"
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+// Stats holds summary statistics for a set of samples
+type Stats = math.Stats
+
+// PercentileMethod selects how percentiles are computed between samples
+type PercentileMethod = math.PercentileMethod
+
+// Percentile methods, see the math package for their definitions
+const (
+	NearestRank  = math.NearestRank
+	Interpolated = math.Interpolated
+)
+
+// JitterMethod selects how jitter is computed from the latency probes
+type JitterMethod string
+
+const (
+	// Spread is the variance of the latency samples, how widely they are
+	// spread regardless of order
+	Spread JitterMethod = "spread"
+
+	// Consecutive is the mean absolute difference between consecutive
+	// probes, how much latency changes from one probe to the next
+	Consecutive JitterMethod = "consecutive"
+)
+
+// LatencyMethod selects what each latency probe measures
+type LatencyMethod string
+
+const (
+	// TTFBLatency is the time to first byte of a small download minus the
+	// server processing time reported in Server-Timing
+	TTFBLatency LatencyMethod = "ttfb"
+
+	// TCPLatency is the time to complete the TCP handshake, one network
+	// round trip without any HTTP or TLS processing, closest to ICMP ping.
+	// Through a proxy it measures the round trip to the proxy.
+	TCPLatency LatencyMethod = "tcp"
+)
+
+// UploadTiming selects how the transfer time of an upload is measured
+type UploadTiming string
+
+const (
+	// ServerTiming uses the processing time the server reports in its
+	// Server-Timing header, falling back to ClientTiming when it is missing
+	ServerTiming UploadTiming = "server"
+
+	// ClientTiming times from when the client starts sending the body until
+	// the response arrives, after the server has read all of it. This
+	// includes a round trip and any server processing, so it slightly
+	// underestimates the speed of small uploads.
+	ClientTiming UploadTiming = "client"
+)
+
+// AIMScores holds the AIM classification for each experience
+type AIMScores = analysis.AIMScores
+
+// Stability labels how consistent the speed samples were
+type Stability = analysis.Stability
+
+// Grade is an overall letter grade for the connection with the sub-score of
+// each metric, see analysis.ConnectionGrade for the rubric
+type Grade = analysis.Grade
+
+// Result holds the measurements from a single speed test run
+type Result struct {
+	Started time.Time `json:"-"`
+
+	// Version identifies the tool that produced the result. Run leaves it
+	// empty for callers to fill in.
+	Version string `json:"version,omitempty"`
+
+	// Timestamp is Started rendered for output. Run leaves it empty so callers
+	// can choose the format.
+	Timestamp string `json:"timestamp"`
+
+	// Clock is the offset of the local clock from an NTP server. Run leaves
+	// it nil for callers that check the clock to fill in.
+	Clock *ClockOffset `json:"clock,omitempty"`
+
+	ServerCity string  `json:"server_city"`
+	ServerColo string  `json:"server_colo"`
+	IP         string  `json:"ip"`
+	Location   string  `json:"location"`
+	Latency    float64 `json:"latency_ms"`
+	Jitter     float64 `json:"jitter_ms"`
+
+	JitterMethod  JitterMethod  `json:"jitter_method"`
+	LatencyMethod LatencyMethod `json:"latency_method"`
+
+	// ServerTimingIgnored is set when Options.IgnoreServerTiming left the
+	// server's processing time out of latency and uploads
+	ServerTimingIgnored bool `json:"server_timing_ignored,omitempty"`
+
+	TTFB       float64 `json:"ttfb_ms"`
+	TLSVersion string  `json:"tls_version"`
+
+	// LatencyMode is the most common latency rounded to Options.ModeBucket,
+	// only set when a bucket is configured
+	LatencyMode float64 `json:"latency_mode_ms,omitempty"`
+
+	// LatencyProbes is the number of latency probes that completed, which
+	// is fewer than requested if the latency budget ran out
+	LatencyProbes int `json:"latency_probes"`
+
+	// LoadedLatency is the latency under load, only set when
+	// Options.LoadedLatency or Options.UploadLoadedLatency is enabled. With
+	// only the latter, just its upload fields are filled in.
+	LoadedLatency *LoadedLatency `json:"loaded_latency,omitempty"`
+
+	Downloads  []TierResult `json:"downloads,omitempty"`
+	Download   float64      `json:"download_mbps"`
+	Uploads    []TierResult `json:"uploads,omitempty"`
+	Upload     float64      `json:"upload_mbps"`
+	Percentile float64      `json:"percentile"`
+
+	// DownloadBytesPerSecond and UploadBytesPerSecond are Download and
+	// Upload in bytes per second, for tools that prefer raw units
+	DownloadBytesPerSecond float64 `json:"download_bytes_per_second"`
+	UploadBytesPerSecond   float64 `json:"upload_bytes_per_second"`
+
+	// UploadTiming is how Upload was measured. UploadServer and UploadClient
+	// are the aggregate upload speed by each measurement for comparison.
+	UploadTiming UploadTiming `json:"upload_timing"`
+	UploadServer float64      `json:"upload_server_timing_mbps"`
+	UploadClient float64      `json:"upload_client_timing_mbps"`
+
+	// UploadServerBytesPerSecond and UploadClientBytesPerSecond are
+	// UploadServer and UploadClient in bytes per second
+	UploadServerBytesPerSecond float64 `json:"upload_server_timing_bytes_per_second"`
+	UploadClientBytesPerSecond float64 `json:"upload_client_timing_bytes_per_second"`
+
+	// DownloadStats and UploadStats summarize all samples across tiers
+	DownloadStats SpeedStats `json:"download_stats"`
+	UploadStats   SpeedStats `json:"upload_stats"`
+
+	// DownloadPercentiles and UploadPercentiles hold the percentiles
+	// requested in Options.Percentiles, in the order requested
+	DownloadPercentiles []PercentileValue `json:"download_percentiles,omitempty"`
+	UploadPercentiles   []PercentileValue `json:"upload_percentiles,omitempty"`
+
+	// DownloadStability rates how consistent the download samples were,
+	// which says how far Download can be trusted
+	DownloadStability Stability `json:"download_stability"`
+
+	// DownloadCPU is the CPU time the process used while downloading as a
+	// percentage of one core, 0 where the platform does not report it.
+	// CPUBound is set when it is so high that the download was likely
+	// limited by decrypting TLS on this device rather than by the network.
+	DownloadCPU float64 `json:"download_cpu_percent,omitempty"`
+	CPUBound    bool    `json:"cpu_bound"`
+
+	PercentileMethod PercentileMethod `json:"percentile_method"`
+
+	AIM AIMScores `json:"aim"`
+
+	Grade Grade `json:"grade"`
+
+	// BDP is the download bandwidth-delay product in bytes, the TCP window
+	// needed to fill the link
+	BDP float64 `json:"bdp_bytes"`
+
+	Timings PhaseTimings `json:"timings"`
+
+	Redirects []string `json:"redirects,omitempty"`
+
+	Reliability Reliability `json:"reliability"`
+
+	Traffic Traffic `json:"traffic"`
+
+	// Warnings lists non-fatal issues that limit how far the numbers can be
+	// trusted, e.g. measurements that were left out or uploads without a
+	// Server-Timing header. Callers may add their own, such as clock skew.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Interception lists signs that a captive portal or intercepting proxy
+	// answered instead of Cloudflare, in which case the results are not trustworthy
+	Interception []string `json:"interception_warnings,omitempty"`
+}
+
+// Reliability tallies the requests made during a run and how many failed
+// before a response was received, e.g. on connection errors or timeouts
+type Reliability struct {
+	Attempts       int     `json:"attempts"`
+	Failed         int     `json:"failed"`
+	SuccessPercent float64 `json:"success_percent"`
+}
+
+// PhaseTimings records how long each phase of a run took, in seconds
+type PhaseTimings struct {
+	Latency  float64 `json:"latency_s"`
+	Metadata float64 `json:"metadata_s"`
+	Download float64 `json:"download_s"`
+	Upload   float64 `json:"upload_s"`
+	Total    float64 `json:"total_s"`
+}
+
+// TierResult holds the median speed measured for a single payload size
+type TierResult struct {
+	Label string     `json:"label"`
+	Bytes int        `json:"bytes"`
+	Speed float64    `json:"mbps"`
+	Stats SpeedStats `json:"stats"`
+
+	// BytesPerSecond is Speed in bytes per second
+	BytesPerSecond float64 `json:"bytes_per_second"`
+
+	// TTFB is the median time to first byte in ms, showing how the server's
+	// response time scales with the requested size. Only set for downloads.
+	TTFB float64 `json:"ttfb_ms,omitempty"`
+
+	// Received is the median number of bytes actually returned per download,
+	// which Speed is computed from. It differs from Bytes when the service
+	// does not return exactly the size requested. Only set for downloads.
+	Received int64 `json:"received_bytes,omitempty"`
+
+	// Retried is the number of failed measurements attempted again in a
+	// second pass, and Failed the number that still failed and were left out
+	Retried int `json:"retried,omitempty"`
+	Failed  int `json:"failed,omitempty"`
+
+	// Discarded is the number of measurements left out for transferring
+	// faster than Options.MinSampleDuration
+	Discarded int `json:"discarded,omitempty"`
+
+	// Samples is the number of successful measurements, only set when
+	// Options.Sampler is not a FixedSampler and the number can vary
+	Samples int `json:"samples,omitempty"`
+
+	// Excluded is set when the tier was left out of the aggregate speed
+	// because it is too small to measure a fast link, see
+	// Options.SkipSmallTiers
+	Excluded bool `json:"excluded,omitempty"`
+}
+
+// PercentileValue is the speed in Mbps at a percentile of the samples
+type PercentileValue struct {
+	Percentile     float64 `json:"percentile"`
+	Mbps           float64 `json:"mbps"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+}
+
+// BytesPerSecond converts a speed in Mbps to bytes per second
+func BytesPerSecond(mbps float64) float64 {
+	return mbps * 1e6 / 8
+}
+
+// setBytesPerSecond fills in the bytes per second of every speed in r
+func (r *Result) setBytesPerSecond() {
+	r.DownloadBytesPerSecond = BytesPerSecond(r.Download)
+	r.UploadBytesPerSecond = BytesPerSecond(r.Upload)
+	r.UploadServerBytesPerSecond = BytesPerSecond(r.UploadServer)
+	r.UploadClientBytesPerSecond = BytesPerSecond(r.UploadClient)
+	r.DownloadStats.setBytesPerSecond()
+	r.UploadStats.setBytesPerSecond()
+	for i := range r.Downloads {
+		r.Downloads[i].BytesPerSecond = BytesPerSecond(r.Downloads[i].Speed)
+		r.Downloads[i].Stats.setBytesPerSecond()
+	}
+	for i := range r.Uploads {
+		r.Uploads[i].BytesPerSecond = BytesPerSecond(r.Uploads[i].Speed)
+		r.Uploads[i].Stats.setBytesPerSecond()
+	}
+}
+
+// SpeedStats summarizes a set of speed samples in Mbps, and in bytes per
+// second in the fields named so. Percentile is taken at Result.Percentile
+// using Result.PercentileMethod.
+type SpeedStats struct {
+	Min        float64 `json:"min"`
+	Median     float64 `json:"median"`
+	Percentile float64 `json:"percentile"`
+	Max        float64 `json:"max"`
+
+	MinBytesPerSecond        float64 `json:"min_bytes_per_second"`
+	MedianBytesPerSecond     float64 `json:"median_bytes_per_second"`
+	PercentileBytesPerSecond float64 `json:"percentile_bytes_per_second"`
+	MaxBytesPerSecond        float64 `json:"max_bytes_per_second"`
+}
+
+func (s *SpeedStats) setBytesPerSecond() {
+	s.MinBytesPerSecond = BytesPerSecond(s.Min)
+	s.MedianBytesPerSecond = BytesPerSecond(s.Median)
+	s.PercentileBytesPerSecond = BytesPerSecond(s.Percentile)
+	s.MaxBytesPerSecond = BytesPerSecond(s.Max)
+}
@@ -0,0 +1,47 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxResumes bounds how many times a single download is resumed after its
+// connection drops
+const maxResumes = 3
+
+// resumeDownload continues a GET whose body failed with readErr after
+// received bytes, requesting the remainder with a Range header and reading it
+// to the end. The time spent reconnecting counts towards the transfer, so a
+// resumed sample is slower but still reflects the connection. It returns the
+// total bytes received, and readErr if the server does not honor the range.
+// Reads are reported to watchdog, which may be nil.
+func (c *Client) resumeDownload(ctx context.Context, httpClient *http.Client, url string, received int64, readErr error, watchdog *stallWatchdog) (int64, error) {
+	for attempt := 0; attempt < maxResumes && ctx.Err() == nil; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return received, err
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", received))
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			readErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			return received, fmt.Errorf("%w (resuming failed: %s)", readErr, resp.Status)
+		}
+
+		n, err := io.Copy(io.Discard, c.limit(ctx, watchdog.watch(resp.Body)))
+		resp.Body.Close()
+		received += n
+		if err == nil {
+			return received, nil
+		}
+		readErr = err
+	}
+	return received, readErr
+}
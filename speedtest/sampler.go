@@ -0,0 +1,88 @@
+package speedtest
+
+import (
+	"context"
+
+	"github.com/coleaeason/cloudflare-speed/internal/math"
+)
+
+// Sampler schedules the measurements of one size tier: how many are taken
+// and when sampling stops. Options.Sampler sets the strategy for every tier.
+type Sampler interface {
+	// Sample calls take until the tier has been measured enough. iterations
+	// is the tier's nominal count. Each call of take makes one measurement
+	// and returns the speeds gathered so far in Mbps, leaving out failed and
+	// discarded measurements. An error from take ends the run and must be
+	// returned. take may be called from several goroutines at once.
+	Sample(ctx context.Context, iterations int, take func() ([]float64, error)) error
+}
+
+// FixedSampler measures each tier exactly its nominal number of times, the
+// default
+type FixedSampler struct{}
+
+// Sample calls take iterations times
+func (FixedSampler) Sample(ctx context.Context, iterations int, take func() ([]float64, error)) error {
+	for i := 0; i < iterations; i++ {
+		if _, err := take(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StableSampler measures each tier until the 95% confidence interval of its
+// mean speed is within Threshold of the mean, e.g. 0.05 for ±5%. It is used
+// when Options.UntilStable is set.
+type StableSampler struct {
+	Threshold float64
+}
+
+// A StableSampler takes at least minStableSamples, enough for a meaningful
+// confidence interval, and at most stableIterationFactor times a tier's
+// nominal count so a noisy link cannot sample forever. Tiers of fewer
+// iterations, the largest sizes, keep their fixed count.
+const (
+	minStableSamples      = 3
+	stableIterationFactor = 3
+)
+
+// Sample calls take until the speeds converge or the limit is reached
+func (s StableSampler) Sample(ctx context.Context, iterations int, take func() ([]float64, error)) error {
+	for i := 0; i < s.limit(iterations); i++ {
+		speeds, err := take()
+		if err != nil {
+			return err
+		}
+		if s.stable(speeds) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// limit is the most measurements taken of a tier of the given nominal count
+func (s StableSampler) limit(iterations int) int {
+	if iterations < minStableSamples {
+		return iterations
+	}
+	return iterations * stableIterationFactor
+}
+
+// stable reports whether the confidence interval of speeds is narrow enough
+func (s StableSampler) stable(speeds []float64) bool {
+	if len(speeds) < minStableSamples {
+		return false
+	}
+	mean := math.Average(speeds)
+	return mean > 0 && math.ConfidenceInterval(speeds)/mean <= s.Threshold
+}
+
+// sampleCount returns the number of speeds for TierResult.Samples, which is
+// only reported when the sampler can vary it
+func (c *Client) sampleCount(speeds []float64) int {
+	if _, fixed := c.opts.Sampler.(FixedSampler); fixed {
+		return 0
+	}
+	return len(speeds)
+}
@@ -0,0 +1,57 @@
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// sampleSeries returns a measurement function that appends the next of
+// speeds, cycling, to the samples it returns, and the number of calls made
+func sampleSeries(speeds ...float64) (func() ([]float64, error), *int) {
+	var taken []float64
+	calls := new(int)
+	return func() ([]float64, error) {
+		taken = append(taken, speeds[*calls%len(speeds)])
+		*calls++
+		return taken, nil
+	}, calls
+}
+
+func TestStableSamplerStoppingRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		speeds     []float64
+		iterations int
+		threshold  float64
+		want       int
+	}{
+		// Identical speeds are stable as soon as there are enough of them
+		{"stops at the minimum", []float64{100}, 10, 0.05, minStableSamples},
+		{"stops at the cap", []float64{10, 200}, 10, 0.05, 10 * stableIterationFactor},
+		{"few iterations keep their count", []float64{10, 200}, 2, 0.05, 2},
+		// ±27% after three samples and ±14% after the fourth
+		{"converges", []float64{100, 120, 100, 110}, 10, 0.2, 4},
+	}
+	for _, tt := range tests {
+		take, calls := sampleSeries(tt.speeds...)
+		if err := (StableSampler{Threshold: tt.threshold}).Sample(context.Background(), tt.iterations, take); err != nil {
+			t.Fatal(err)
+		}
+		if *calls != tt.want {
+			t.Errorf("%s: took %d samples, want %d", tt.name, *calls, tt.want)
+		}
+	}
+}
+
+func TestStableSamplerStopsOnError(t *testing.T) {
+	failure := errors.New("failed")
+	calls := 0
+	err := StableSampler{Threshold: 0.05}.Sample(context.Background(), 10, func() ([]float64, error) {
+		calls++
+		return nil, failure
+	})
+	if err != failure || calls != 1 {
+		t.Errorf("got %v after %d calls, want the first error", err, calls)
+	}
+}
@@ -0,0 +1,156 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+)
+
+// The loopback server injects a fixed delay before each response and paces
+// both directions at a fixed rate, so the figures a run should report are
+// known in advance
+const (
+	selftestDelay     = 25 * time.Millisecond
+	selftestRate      = 50e6 // bits per second
+	selftestTolerance = 0.25 // allowed relative error in throughput
+	selftestChunk     = 16 * 1024
+)
+
+// selftestTiers are large enough that the pacing, not request overhead,
+// dominates each transfer
+var selftestTiers = []SizeTier{
+	{Label: "1MB", Bytes: 1000000, Iterations: 3},
+	{Label: "5MB", Bytes: 5000000, Iterations: 2},
+}
+
+// SelfTestCheck is a figure reported by a self-test run and the range the
+// loopback server's delay and rate put it in
+type SelfTestCheck struct {
+	Name     string
+	Got      float64
+	Min, Max float64
+	Unit     string
+}
+
+// Passed reports whether the figure is within its expected range
+func (c SelfTestCheck) Passed() bool {
+	return c.Got >= c.Min && c.Got <= c.Max
+}
+
+// SelfTest runs a full measurement against a local loopback server with a
+// known delay and rate and returns how the latency, download and upload
+// compare with them. It exercises the measurement logic without depending
+// on the network or the service.
+func SelfTest(ctx context.Context) ([]SelfTestCheck, error) {
+	server := httptest.NewServer(loopbackHandler())
+	defer server.Close()
+
+	result, err := NewClient(Options{
+		Backend:       &loopbackBackend{url: server.URL},
+		DownloadTiers: selftestTiers,
+		UploadTiers:   selftestTiers,
+		Percentile:    50,
+	}).Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := selftestRate / 1e6
+	delay := float64(selftestDelay) / float64(time.Millisecond)
+	return []SelfTestCheck{
+		// Loopback adds well under a millisecond on top of the injected delay
+		{"Latency", result.Latency, delay, delay + 15, "ms"},
+		{"Download", result.Download, rate * (1 - selftestTolerance), rate * (1 + selftestTolerance), "Mbps"},
+		{"Upload", result.Upload, rate * (1 - selftestTolerance), rate * (1 + selftestTolerance), "Mbps"},
+	}, nil
+}
+
+// loopbackBackend points a Client at the loopback server
+type loopbackBackend struct {
+	url string
+}
+
+func (b *loopbackBackend) Download(ctx context.Context, bytes int) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, "GET", b.url+"/__down?bytes="+strconv.Itoa(bytes), nil)
+}
+
+func (b *loopbackBackend) Upload(ctx context.Context, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, "POST", b.url+"/__up", body)
+}
+
+func (b *loopbackBackend) Trace(ctx context.Context, client *http.Client) (*TraceInfo, error) {
+	return &TraceInfo{Colo: "LOOP", City: "Loopback", IP: "127.0.0.1"}, nil
+}
+
+// loopbackHandler serves the download and upload endpoints, delaying each
+// response by selftestDelay and pacing bodies at selftestRate
+func loopbackHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/__down", func(w http.ResponseWriter, r *http.Request) {
+		bytes, err := strconv.Atoi(r.URL.Query().Get("bytes"))
+		if err != nil || bytes < 0 {
+			http.Error(w, "invalid bytes", http.StatusBadRequest)
+			return
+		}
+		time.Sleep(selftestDelay)
+		w.Header().Set("Content-Length", strconv.Itoa(bytes))
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		// Each chunk is written once the time it takes at the target rate
+		// has passed since the headers, so the body arrives at that rate
+		chunk := make([]byte, selftestChunk)
+		started := time.Now()
+		for written := 0; written < bytes; {
+			n := len(chunk)
+			if bytes-written < n {
+				n = bytes - written
+			}
+			time.Sleep(time.Until(started.Add(paceDuration(written + n))))
+			if _, err := w.Write(chunk[:n]); err != nil {
+				return
+			}
+			written += n
+		}
+	})
+	mux.HandleFunc("/__up", func(w http.ResponseWriter, r *http.Request) {
+		// Read at the target rate and report the time taken in
+		// Server-Timing, as the real service does
+		chunk := make([]byte, selftestChunk)
+		var started time.Time
+		read := 0
+		for {
+			n, err := r.Body.Read(chunk)
+			if n > 0 && started.IsZero() {
+				started = time.Now()
+			}
+			read += n
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return
+			}
+			time.Sleep(time.Until(started.Add(paceDuration(read))))
+		}
+		var dur float64
+		if !started.IsZero() {
+			dur = float64(time.Since(started)) / float64(time.Millisecond)
+		}
+		time.Sleep(selftestDelay)
+		w.Header().Set("Server-Timing", fmt.Sprintf("cfRequestDuration;dur=%.3f", dur))
+	})
+	return mux
+}
+
+// paceDuration is how long transferring bytes takes at selftestRate
+func paceDuration(bytes int) time.Duration {
+	return time.Duration(float64(bytes) * 8 / selftestRate * float64(time.Second))
+}
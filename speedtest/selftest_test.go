@@ -0,0 +1,21 @@
+package speedtest
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSelfTestLoopback(t *testing.T) {
+	if testing.Short() {
+		t.Skip("paced transfers take several seconds")
+	}
+	checks, err := SelfTest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range checks {
+		if !check.Passed() {
+			t.Errorf("%s = %.2f %s, want %.2f to %.2f", check.Name, check.Got, check.Unit, check.Min, check.Max)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package speedtest
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseServerTiming returns the first dur parameter in a Server-Timing header
+// in milliseconds, or 0 when there is none that can be trusted. A header such
+// as
+//
+//	cfRequestDuration;dur=12.5, edge;desc="a;b, c";dur=3
+//
+// is a comma separated list of metrics, each a name followed by parameters
+// separated by semicolons. Quoted values may contain either separator.
+//
+// A duration that is negative, not finite or longer than elapsed, the time
+// the whole request took on the client, cannot be right. It is dropped so the
+// caller falls back to client timing rather than reporting an absurd speed.
+func parseServerTiming(header string, elapsed time.Duration) float64 {
+	for _, metric := range splitQuoted(header, ',') {
+		for _, param := range splitQuoted(metric, ';')[1:] {
+			name, value, ok := strings.Cut(param, "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "dur") {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if strings.HasPrefix(value, `"`) {
+				if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+					return 0
+				}
+				value = value[1 : len(value)-1]
+			}
+			dur, err := strconv.ParseFloat(value, 64)
+			if err != nil || dur < 0 || math.IsInf(dur, 0) || math.IsNaN(dur) {
+				return 0
+			}
+			if elapsed > 0 && dur > elapsed.Seconds()*1000 {
+				return 0
+			}
+			return dur
+		}
+	}
+	return 0
+}
+
+// splitQuoted splits s at each sep that is not inside a double quoted string
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped := false, false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,97 @@
+package speedtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   float64
+	}{
+		{"single metric", "cfRequestDuration;dur=12.5", 12.5},
+		{"whitespace and case", " cfRequestDuration ; DUR = 7 ", 7},
+		{"quoted value", `cfRequestDuration;dur="3.25"`, 3.25},
+		{"desc before dur", "edge;desc=upload;dur=4", 4},
+		{"separators in quoted desc", `edge;desc="a;dur=1, b";dur=9`, 9},
+		{"escaped quote in desc", `edge;desc="say \"hi\";dur=1";dur=6`, 6},
+		{"first of multiple metrics", "cfRequestDuration;dur=12, origin;dur=30", 12},
+		{"metric without dur first", "cache;desc=hit, cfRequestDuration;dur=8", 8},
+		{"zero", "cfRequestDuration;dur=0", 0},
+		{"empty", "", 0},
+		{"no dur", "cfRequestDuration;desc=x", 0},
+		{"name only", "cfRequestDuration", 0},
+		{"dur without value", "cfRequestDuration;dur", 0},
+		{"empty dur", "cfRequestDuration;dur=", 0},
+		{"malformed dur", "cfRequestDuration;dur=12ms", 0},
+		{"malformed dur before valid", "a;dur=abc, b;dur=5", 0},
+		{"negative", "cfRequestDuration;dur=-5", 0},
+		{"NaN", "cfRequestDuration;dur=NaN", 0},
+		{"Inf", "cfRequestDuration;dur=Inf", 0},
+		{"negative Inf", "cfRequestDuration;dur=-Inf", 0},
+		{"overflow", "cfRequestDuration;dur=1e400", 0},
+		{"huge", "cfRequestDuration;dur=1e300", 0},
+		{"longer than the request", "cfRequestDuration;dur=1000.5", 0},
+		{"unterminated quote", `cfRequestDuration;dur="12`, 0},
+		{"only separators", ",;,;", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseServerTiming(tt.header, time.Second); got != tt.want {
+				t.Errorf("parseServerTiming(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseServerTimingUnknownElapsed(t *testing.T) {
+	// Without a request duration to compare against only the value is checked
+	if got := parseServerTiming("cfRequestDuration;dur=5000", 0); got != 5000 {
+		t.Errorf("got %v, want 5000", got)
+	}
+}
+
+func TestParseServerTimingLongHeader(t *testing.T) {
+	header := strings.Repeat(`m;desc="x, y; z",`, 10000) + "cfRequestDuration;dur=2"
+	if got := parseServerTiming(header, time.Second); got != 2 {
+		t.Errorf("got %v, want 2", got)
+	}
+}
+
+func TestServerTimingDuplicatedHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    float64
+	}{
+		{"dur in second header", []string{"cache;desc=hit", "cfRequestDuration;dur=5"}, 5},
+		{"dur in both headers", []string{"cfRequestDuration;dur=5", "cfRequestDuration;dur=7"}, 5},
+		{"malformed first header", []string{"cfRequestDuration;dur=x", "cfRequestDuration;dur=7"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(20 * time.Millisecond)
+				for _, h := range tt.headers {
+					w.Header().Add("Server-Timing", h)
+				}
+			}))
+			defer srv.Close()
+			c := NewClient(Options{Host: srv.Listener.Addr().String(), Scheme: "http"})
+
+			timing, err := c.upload(context.Background(), 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if timing.serverTiming != tt.want {
+				t.Errorf("serverTiming = %v, want %v", timing.serverTiming, tt.want)
+			}
+		})
+	}
+}
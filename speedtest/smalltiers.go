@@ -0,0 +1,50 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Options.SkipSmallTiers probes the link with a single download of
+// fastLinkProbeBytes. At fastLinkMbps or more, tiers that would transfer in
+// under minTierTransfer are left out of the aggregate, since their samples
+// measure latency and TCP slow start more than throughput.
+const (
+	fastLinkProbeBytes = 10001000
+	fastLinkMbps       = 250
+	minTierTransfer    = 50 * time.Millisecond
+)
+
+// smallTiers returns the indexes of the tiers too small to measure the link,
+// none unless the probe finds a fast link. The largest tier is never excluded.
+func (c *Client) smallTiers(ctx context.Context, tiers []SizeTier) (map[int]bool, error) {
+	timing, err := c.download(ctx, fastLinkProbeBytes)
+	if err != nil {
+		if c.aborts(err) {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return nil, nil
+	}
+	mbps := measureSpeed(int(timing.received), timing.ended.Sub(timing.ttfb))
+	if mbps < fastLinkMbps {
+		return nil, nil
+	}
+
+	largest := 0
+	for i, tier := range tiers {
+		if tier.Bytes > tiers[largest].Bytes {
+			largest = i
+		}
+	}
+	excluded := make(map[int]bool)
+	for i, tier := range tiers {
+		transfer := time.Duration(float64(tier.Bytes) * 8 / (mbps * 1e6) * float64(time.Second))
+		if i != largest && transfer < minTierTransfer {
+			excluded[i] = true
+		}
+	}
+	return excluded, nil
+}
@@ -0,0 +1,98 @@
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/coleaeason/cloudflare-speed/internal/iocount"
+)
+
+// errStalled is returned for a request aborted by its stall watchdog. It is
+// retried like any other transport error.
+var errStalled = errors.New("transfer stalled")
+
+// MinStallTimeout is the shortest Options.StallTimeout the command line
+// accepts. Shorter timeouts abort healthy transfers on ordinary scheduling
+// and network hiccups.
+const MinStallTimeout = 100 * time.Millisecond
+
+// stallWatchdog cancels a request when neither its request nor its response
+// body makes progress for timeout. This catches connections that stay open
+// but stop moving data, which an overall timeout only notices much later.
+type stallWatchdog struct {
+	timeout  time.Duration
+	clock    Clock
+	cancel   context.CancelFunc
+	progress int64 // unix nanoseconds of the last read, accessed atomically
+	stalled  int32
+	done     chan struct{}
+}
+
+// watchStalls returns a context for a request that is cancelled once it
+// stalls for timeout as measured by clock. Reads through watch count as
+// progress. stop must be called once the request finishes.
+func watchStalls(ctx context.Context, timeout time.Duration, clock Clock) (context.Context, *stallWatchdog) {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &stallWatchdog{
+		timeout:  timeout,
+		clock:    clock,
+		cancel:   cancel,
+		progress: clock.Now().UnixNano(),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return ctx, w
+}
+
+func (w *stallWatchdog) run() {
+	// Progress is checked four times per timeout, and no more often than
+	// every millisecond for the tiny timeouts a library caller might set.
+	// The checks are scheduled by the system clock but compare clock's time.
+	interval := w.timeout / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			last := time.Unix(0, atomic.LoadInt64(&w.progress))
+			if w.clock.Now().Sub(last) >= w.timeout {
+				atomic.StoreInt32(&w.stalled, 1)
+				w.cancel()
+				return
+			}
+		}
+	}
+}
+
+// watch returns r with every successful read counted as progress. A nil
+// watchdog returns r unchanged.
+func (w *stallWatchdog) watch(r io.Reader) io.Reader {
+	if w == nil {
+		return r
+	}
+	return iocount.NewCountingReader(r, func(int) {
+		atomic.StoreInt64(&w.progress, w.clock.Now().UnixNano())
+	})
+}
+
+// err replaces err with errStalled if the watchdog aborted the request
+func (w *stallWatchdog) err(err error) error {
+	if err != nil && atomic.LoadInt32(&w.stalled) == 1 {
+		return fmt.Errorf("%w: no progress for %s", errStalled, w.timeout)
+	}
+	return err
+}
+
+func (w *stallWatchdog) stop() {
+	close(w.done)
+	w.cancel()
+}
@@ -0,0 +1,56 @@
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchStallsTinyTimeout(t *testing.T) {
+	// A timeout under 4ns used to make a zero ticker interval and panic
+	ctx, w := watchStalls(context.Background(), 3*time.Nanosecond, SystemClock{})
+	defer w.stop()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("request without progress was not cancelled")
+	}
+	if err := w.err(ctx.Err()); !errors.Is(err, errStalled) {
+		t.Errorf("err = %v, want %v", err, errStalled)
+	}
+}
+
+func TestWatchStallsFollowsClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ctx, w := watchStalls(context.Background(), 10*time.Millisecond, clock)
+	defer w.stop()
+
+	// Real time passing is not a stall while the clock stands still
+	select {
+	case <-ctx.Done():
+		t.Fatal("request was cancelled before the clock moved")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Progress restarts the timeout
+	clock.Advance(9 * time.Millisecond)
+	w.watch(strings.NewReader("x")).Read(make([]byte, 1))
+	clock.Advance(9 * time.Millisecond)
+	select {
+	case <-ctx.Done():
+		t.Fatal("request was cancelled despite progress")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Millisecond)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("request without progress for the timeout was not cancelled")
+	}
+	if err := w.err(ctx.Err()); !errors.Is(err, errStalled) {
+		t.Errorf("err = %v, want %v", err, errStalled)
+	}
+}
@@ -0,0 +1,114 @@
+package speedtest
+
+import (
+	"io"
+	"sync"
+
+	"github.com/coleaeason/cloudflare-speed/internal/iocount"
+)
+
+// Phases of a run that traffic is attributed to
+const (
+	phaseLatency  = "latency"
+	phaseMetadata = "metadata"
+	phaseDownload = "download"
+	phaseUpload   = "upload"
+)
+
+// PhaseTraffic counts the requests made and the body bytes moved during a
+// phase. Headers and TLS overhead are not included, so a router will count
+// slightly more.
+type PhaseTraffic struct {
+	Requests      int   `json:"requests"`
+	BytesSent     int64 `json:"bytes_sent"`
+	BytesReceived int64 `json:"bytes_received"`
+}
+
+func (t *PhaseTraffic) add(o PhaseTraffic) {
+	t.Requests += o.Requests
+	t.BytesSent += o.BytesSent
+	t.BytesReceived += o.BytesReceived
+}
+
+// Traffic breaks down the requests and bytes of a run by phase
+type Traffic struct {
+	Latency  PhaseTraffic `json:"latency"`
+	Metadata PhaseTraffic `json:"metadata"`
+	Download PhaseTraffic `json:"download"`
+	Upload   PhaseTraffic `json:"upload"`
+	Total    PhaseTraffic `json:"total"`
+}
+
+// trafficTally attributes traffic to the phase that is running
+type trafficTally struct {
+	mu     sync.Mutex
+	phase  string
+	phases map[string]*PhaseTraffic
+}
+
+func newTrafficTally() *trafficTally {
+	return &trafficTally{phases: make(map[string]*PhaseTraffic)}
+}
+
+// start attributes traffic from now on to phase
+func (t *trafficTally) start(phase string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase = phase
+}
+
+// request records a request sending sent body bytes and returns the phase it
+// belongs to, so its response can be attributed to the same phase later
+func (t *trafficTally) request(sent int64) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.get(t.phase)
+	p.Requests++
+	p.BytesSent += sent
+	return t.phase
+}
+
+func (t *trafficTally) received(phase string, n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(phase).BytesReceived += n
+}
+
+func (t *trafficTally) get(phase string) *PhaseTraffic {
+	p, ok := t.phases[phase]
+	if !ok {
+		p = &PhaseTraffic{}
+		t.phases[phase] = p
+	}
+	return p
+}
+
+func (t *trafficTally) traffic() Traffic {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	traffic := Traffic{
+		Latency:  *t.get(phaseLatency),
+		Metadata: *t.get(phaseMetadata),
+		Download: *t.get(phaseDownload),
+		Upload:   *t.get(phaseUpload),
+	}
+	for _, p := range t.phases {
+		traffic.Total.add(*p)
+	}
+	return traffic
+}
+
+// countingBody attributes the bytes read from a response body to a phase
+type countingBody struct {
+	*iocount.CountingReader
+	io.Closer
+}
+
+func newCountingBody(body io.ReadCloser, tally *trafficTally, phase string) *countingBody {
+	return &countingBody{
+		CountingReader: iocount.NewCountingReader(body, func(n int) {
+			tally.received(phase, int64(n))
+		}),
+		Closer: body,
+	}
+}
@@ -0,0 +1,104 @@
+package speedtest
+
+import (
+	"fmt"
+	"sync"
+)
+
+// caveatTally counts issues noticed in responses during a run that make the
+// numbers less trustworthy without failing any measurement
+type caveatTally struct {
+	mu sync.Mutex
+
+	// uploads is the number of uploads measured and noServerTiming those
+	// without a usable Server-Timing duration
+	uploads        int
+	noServerTiming int
+
+	// compressed is the number of responses the server compressed
+	compressed int
+
+	// limited is the number of 429 responses and exhausted the number of
+	// responses saying no requests were left in the rate limit window
+	limited   int
+	exhausted int
+}
+
+func (t *caveatTally) upload(serverTiming bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.uploads++
+	if !serverTiming {
+		t.noServerTiming++
+	}
+}
+
+func (t *caveatTally) compressedResponse() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.compressed++
+}
+
+func (t *caveatTally) rateLimited() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limited++
+}
+
+func (t *caveatTally) rateLimitExhausted() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exhausted++
+}
+
+// warnings describes every non-fatal issue of the finished run r
+func (c *Client) warnings(r *Result) []string {
+	var warnings []string
+	for _, tier := range r.Downloads {
+		if tier.Received > 0 && tier.Received != int64(tier.Bytes) {
+			warnings = append(warnings, fmt.Sprintf("%s download: received %d of %d requested bytes", tier.Label, tier.Received, tier.Bytes))
+		}
+		if tier.Excluded {
+			warnings = append(warnings, fmt.Sprintf("%s download: too small to measure a fast link, left out of the download speed", tier.Label))
+		}
+	}
+	tiers := []struct {
+		direction string
+		tiers     []TierResult
+	}{{"download", r.Downloads}, {"upload", r.Uploads}}
+	for _, d := range tiers {
+		for _, tier := range d.tiers {
+			if tier.Discarded > 0 {
+				warnings = append(warnings, fmt.Sprintf("%s %s: %d measurements too short to count", tier.Label, d.direction, tier.Discarded))
+			}
+			if tier.Failed > 0 {
+				warnings = append(warnings, fmt.Sprintf("%s %s: %d measurements failed after %d were retried", tier.Label, d.direction, tier.Failed, tier.Retried))
+			}
+		}
+	}
+
+	if l := r.LoadedLatency; l != nil && l.DownloadGrade == "" && l.UploadGrade == "" {
+		warnings = append(warnings, "every loaded latency probe failed, so bufferbloat could not be graded")
+	}
+
+	if r.CPUBound {
+		warnings = append(warnings, fmt.Sprintf("downloading used %.0f%% of a CPU core; the speed may be limited by this device rather than the network", r.DownloadCPU))
+	}
+
+	t := c.caveats
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.noServerTiming > 0 && r.UploadTiming == ServerTiming && !r.ServerTimingIgnored {
+		warnings = append(warnings, fmt.Sprintf("%d of %d uploads had no usable Server-Timing header and were timed by the client", t.noServerTiming, t.uploads))
+	}
+	if t.limited > 0 {
+		warnings = append(warnings, fmt.Sprintf("rate limited by the server %d times (HTTP 429); the numbers may reflect the limit rather than the connection", t.limited))
+	}
+	if t.exhausted > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d responses reported the rate limit as used up; further tests may be throttled", t.exhausted))
+	}
+	if t.compressed > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d responses were compressed in transit, so fewer bytes crossed the network than were measured", t.compressed))
+	}
+	return warnings
+}